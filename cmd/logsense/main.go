@@ -34,6 +34,7 @@ func main() {
 	logx.Infof("starting logsense %s: %s", version.String(), cfg.String())
 	if err := ui.Run(ctx, cfg); err != nil {
 		logx.Errorf("logsense exited with error: %v", err)
+		fmt.Fprintln(os.Stderr, "error:", err)
 		os.Exit(1)
 	}
 }
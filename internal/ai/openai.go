@@ -158,6 +158,7 @@ func buildSchemaPrompt(lines []string) string {
 	b.WriteString("  - Use named capture groups with Go syntax (?P<name>...) for each field in schema.fields.\n")
 	b.WriteString("  - Avoid lookarounds, backreferences, inline flags outside the pattern, or unsupported syntax.\n")
 	b.WriteString("  - Prefer anchoring the whole line with ^ and $ when possible.\n")
+	b.WriteString("- If parseStrategy=json: pathOrGroup is a path to the value, e.g. .http.request.method, .items[0].id or .labels[\"app.kubernetes.io/name\"]; use it to pull nested values into named fields.\n")
 	b.WriteString("- timeLayout: RFC3339 if timestamps are ISO-8601; otherwise provide the exact Go time layout for the captured timestamp.\n")
 	b.WriteString("- levelMapping: map common lowercase keys to canonical levels (TRACE, DEBUG, INFO, WARN, ERROR, FATAL) when applicable; empty if not applicable.\n")
 	b.WriteString("- schema.fields: pick meaningful fields actually present in the data. Favor common names when applicable:\n")
@@ -5,7 +5,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strconv"
+	"strings"
+	"time"
+
+	"logsense/internal/parse"
 )
 
 type Theme string
@@ -16,11 +22,21 @@ const (
 )
 
 type Config struct {
-	FilePath         string
+	FilePaths        []string
+	RotatedBase      string
+	Command          string
+	ListenSyslog     string
+	ListenHTTP       string
+	ListenOTLP       string
+	Dir              string
+	Include          string
 	UseStdin         bool
 	Follow           bool
 	MaxBuffer        int
 	BlockSizeMB      int
+	LongLines        string
+	MaxLineBytes     int
+	LongLineLimitMB  int
 	Theme            Theme
 	Offline          bool
 	NoCache          bool
@@ -29,8 +45,25 @@ type Config struct {
 	OpenAITimeoutSec int
 	TimeLayout       string
 	ForceFormat      string
+	RegexPattern     string
+	GrokPatterns     []string
+	Grok             *parse.GrokLibrary // bundled patterns plus GrokPatterns; nil when there are none
+	JSONDepth        int
+	JSONArrays       string
 	ExportFormat     string
 	ExportOut        string
+	Multiline        string // --multiline rule list, resolved by the ingest stage
+	MultilineStart   *regexp.Regexp
+	MultilineTimeout time.Duration
+	MultilineLines   int
+	Since            time.Time
+	Until            time.Time
+	SinceLast        bool
+	Replay           bool
+	ReplaySpeed      float64
+	Record           string
+	Encoding         string
+	Load             string
 
 	// Internal
 	IsPipedStdin bool
@@ -51,11 +84,23 @@ func Load() (*Config, error) {
 	fs := flag.NewFlagSet("logsense", flag.ContinueOnError)
 	fs.SetOutput(os.Stderr)
 
-	fs.StringVar(&cfg.FilePath, "file", "", "path to log file")
+	var files stringList
+	fs.Var(&files, "file", "path or glob of a log file (repeatable)")
+	fs.StringVar(&cfg.RotatedBase, "rotated", "", "path of a live log file; its logrotate siblings (app.log.1, app.log.2.gz, ...) are read oldest-first before it")
+	fs.StringVar(&cfg.Dir, "dir", "", "watch a directory: read every matching file and attach files created later (e.g. /var/log/containers)")
+	fs.StringVar(&cfg.Include, "include", "*", "file name glob for --dir (e.g. '*.log')")
+	fs.StringVar(&cfg.Command, "cmd", "", "run a shell command and read its stdout/stderr, restarting it when it exits (e.g. \"kubectl logs -f deploy/api\")")
+	fs.StringVar(&cfg.Load, "load", "", "reopen a capture written by --record (source tags and arrival times included)")
+	fs.StringVar(&cfg.ListenSyslog, "listen-syslog", "", "receive syslog (RFC3164/RFC5424) on udp://host:port, tcp://host:port, unix:///path or unixgram:///path")
+	fs.StringVar(&cfg.ListenHTTP, "listen-http", "", "accept POSTed NDJSON, text or JSON arrays over HTTP on this address (e.g. :9880)")
+	fs.StringVar(&cfg.ListenOTLP, "listen-otlp", "", "receive OpenTelemetry logs over OTLP/HTTP (JSON or protobuf, POST /v1/logs) on this address (e.g. :4318)")
 	fs.BoolVar(&cfg.Follow, "follow", false, "when reading --file, start in follow mode (tail -f)")
 	fs.BoolVar(&cfg.UseStdin, "stdin", false, "read from stdin (default: auto if piped)")
 	fs.IntVar(&cfg.MaxBuffer, "max-buffer", 200000, "ring buffer size (min 50000)")
 	fs.IntVar(&cfg.BlockSizeMB, "block-size-mb", 0, "when reading a file (no follow), read only the last N MB instead of the whole file (0=all)")
+	fs.StringVar(&cfg.LongLines, "long-lines", "truncate", "lines longer than --max-line-bytes: truncate (tagged with _truncated_bytes) or keep them whole up to --long-line-limit-mb")
+	fs.IntVar(&cfg.MaxLineBytes, "max-line-bytes", 1024*1024, "line length above which --long-lines=truncate cuts a line")
+	fs.IntVar(&cfg.LongLineLimitMB, "long-line-limit-mb", 64, "hard limit for one line with --long-lines=keep; longer lines are truncated")
 	theme := string(ThemeDark)
 	fs.StringVar(&theme, "theme", string(ThemeDark), "theme: dark|light")
 	fs.BoolVar(&cfg.Offline, "offline", false, "disable OpenAI and work offline only")
@@ -64,10 +109,32 @@ func Load() (*Config, error) {
 	fs.StringVar(&cfg.OpenAIBase, "openai-base-url", getenvDefault("LOGSENSE_OPENAI_BASE_URL", ""), "OpenAI base URL override")
 	fs.IntVar(&cfg.OpenAITimeoutSec, "openai-timeout-sec", getenvDefaultInt("LOGSENSE_OPENAI_TIMEOUT_SEC", 120), "OpenAI request timeout in seconds")
 	fs.StringVar(&cfg.TimeLayout, "time-layout", "", "force time layout (Go format)")
-	fs.StringVar(&cfg.ForceFormat, "format", "", "force format: json|regex|logfmt|apache|syslog")
+	fs.StringVar(&cfg.ForceFormat, "format", "", "force format: json|regex|logfmt|apache|syslog|syslog3164|csv")
+	fs.StringVar(&cfg.RegexPattern, "regex-pattern", "", "pattern for --format regex: RE2 with named groups or grok (e.g. '%{IPORHOST:client} %{HTTPDATE:ts}')")
+	var grokFiles stringList
+	fs.Var(&grokFiles, "grok-patterns", "file or directory of grok pattern definitions (NAME pattern per line) added to the bundled set (repeatable)")
+	fs.IntVar(&cfg.JSONDepth, "json-depth", 3, "levels of nested JSON objects flattened into dotted columns (e.g. http.request.method); 0 keeps nested objects whole")
+	fs.StringVar(&cfg.JSONArrays, "json-arrays", "keep", "JSON arrays: keep (one column) or index (elements as key.0, key.1, ...)")
+	fs.StringVar(&cfg.Encoding, "encoding", "auto", "character encoding of file/stdin/command input: auto|utf-8|utf-16le|utf-16be|latin1|windows-1252|shift_jis")
+	fs.StringVar(&cfg.Record, "record", "", "write every raw input line with its source and arrival time to this capture file (reopen with --load)")
 	fs.StringVar(&cfg.ExportFormat, "export", "", "export filtered view: csv|json")
 	fs.StringVar(&cfg.ExportOut, "out", "", "output path for export")
 
+	multilineStart := ""
+	fs.StringVar(&cfg.Multiline, "multiline", "off", "join continuation lines into the previous record: off|auto or a list of indent,caused-by,timestamp")
+	fs.StringVar(&multilineStart, "multiline-start", "", "regex matching the first line of a record; other lines are joined to the previous one")
+	fs.DurationVar(&cfg.MultilineTimeout, "multiline-timeout", time.Second, "flush a pending multiline record (or split CRI line) after this idle time")
+	fs.IntVar(&cfg.MultilineLines, "multiline-max-lines", 500, "maximum physical lines joined into one record")
+
+	since, until := "", ""
+	fs.StringVar(&since, "since", "", "only load records at or after this time (e.g. 2025-01-01T14:02:00Z, \"2025-01-01 14:02\", 14:02 or 30m for 30 minutes ago)")
+	fs.BoolVar(&cfg.SinceLast, "since-last", false, "with --file, only read what was appended since the previous session (handles rotation and truncation)")
+	fs.StringVar(&until, "until", "", "only load records at or before this time (same formats as --since)")
+
+	fs.BoolVar(&cfg.Replay, "replay", false, "with a single --file, re-emit records on the schedule of their timestamps (add --follow to keep tailing afterwards)")
+	replaySpeed := ""
+	fs.StringVar(&replaySpeed, "replay-speed", "1x", "replay speed multiplier (e.g. 10x, 0.5x)")
+
 	showVersion := false
 	fs.BoolVar(&showVersion, "version", false, "print version and exit")
 
@@ -77,21 +144,154 @@ func Load() (*Config, error) {
 	cfg.ShowVersion = showVersion
 	cfg.Theme = Theme(theme)
 
-	// If reading from stdin or no file provided (demo), ignore --follow; only applies to --file.
-	if cfg.UseStdin || cfg.FilePath == "" {
+	if multilineStart != "" {
+		re, err := regexp.Compile(multilineStart)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --multiline-start: %w", err)
+		}
+		cfg.MultilineStart = re
+	}
+
+	var err error
+	now := time.Now()
+	if since != "" {
+		if cfg.Since, err = ParseTimeBound(since, now); err != nil {
+			return nil, fmt.Errorf("invalid --since: %w", err)
+		}
+	}
+	if until != "" {
+		if cfg.Until, err = ParseTimeBound(until, now); err != nil {
+			return nil, fmt.Errorf("invalid --until: %w", err)
+		}
+		if !cfg.Since.IsZero() && cfg.Until.Before(cfg.Since) {
+			return nil, errors.New("--until is before --since")
+		}
+	}
+
+	if len(files) > 0 {
+		paths, err := expandGlobs(files)
+		if err != nil {
+			return nil, err
+		}
+		cfg.FilePaths = paths
+	}
+
+	if cfg.RotatedBase != "" && len(cfg.FilePaths) > 0 {
+		return nil, errors.New("--rotated cannot be combined with --file")
+	}
+	if cfg.Dir != "" {
+		if cfg.RotatedBase != "" || len(cfg.FilePaths) > 0 {
+			return nil, errors.New("--dir cannot be combined with --file or --rotated")
+		}
+		st, err := os.Stat(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			return nil, fmt.Errorf("--dir %s is not a directory", cfg.Dir)
+		}
+		if _, err := filepath.Match(cfg.Include, ""); err != nil {
+			return nil, fmt.Errorf("invalid --include pattern %q: %w", cfg.Include, err)
+		}
+	}
+	if cfg.Load != "" && (cfg.HasFileInput() || cfg.UseStdin || cfg.Command != "") {
+		return nil, errors.New("--load cannot be combined with --file, --rotated, --dir, --stdin or --cmd")
+	}
+	if cfg.Command != "" && (cfg.HasFileInput() || cfg.UseStdin) {
+		return nil, errors.New("--cmd cannot be combined with --file, --rotated, --dir or --stdin")
+	}
+	listeners := 0
+	for _, l := range []string{cfg.ListenSyslog, cfg.ListenHTTP, cfg.ListenOTLP} {
+		if l != "" {
+			listeners++
+		}
+	}
+	if listeners > 1 || (listeners == 1 && (cfg.HasFileInput() || cfg.UseStdin || cfg.Command != "" || cfg.Load != "")) {
+		return nil, errors.New("--listen-syslog, --listen-http and --listen-otlp cannot be combined with each other or with other inputs")
+	}
+
+	if cfg.Command != "" || cfg.Dir != "" {
+		// For a command source, follow means restarting the command when it
+		// exits; for a directory it means watching for new lines and files.
+		// Both are on unless --follow=false was given explicitly.
+		followSet := false
+		fs.Visit(func(f *flag.Flag) { followSet = followSet || f.Name == "follow" })
+		if !followSet {
+			cfg.Follow = true
+		}
+	} else if cfg.UseStdin || !cfg.HasFileInput() {
+		// If reading from stdin or no file provided (demo), ignore --follow; only applies to --file/--rotated.
 		cfg.Follow = false
 	}
 
+	if cfg.SinceLast {
+		if len(cfg.FilePaths) == 0 {
+			return nil, errors.New("--since-last requires --file")
+		}
+		if !cfg.Since.IsZero() || !cfg.Until.IsZero() {
+			return nil, errors.New("--since-last cannot be combined with --since or --until")
+		}
+	}
+	cfg.GrokPatterns = grokFiles
+	if len(cfg.GrokPatterns) > 0 {
+		if cfg.Grok, err = parse.LoadGrokLibrary(cfg.GrokPatterns...); err != nil {
+			return nil, fmt.Errorf("--grok-patterns: %w", err)
+		}
+	}
+	if cfg.RegexPattern != "" {
+		if cfg.ForceFormat == "" {
+			cfg.ForceFormat = "regex"
+		} else if cfg.ForceFormat != "regex" {
+			return nil, errors.New("--regex-pattern requires --format regex")
+		}
+		if err := parse.ValidateRegexPattern(cfg.RegexPattern, cfg.Grok); err != nil {
+			return nil, fmt.Errorf("invalid --regex-pattern: %w", err)
+		}
+	}
+	switch cfg.LongLines {
+	case "truncate", "keep":
+	default:
+		return nil, fmt.Errorf("invalid --long-lines %q (want truncate or keep)", cfg.LongLines)
+	}
+	if cfg.MaxLineBytes <= 0 || cfg.LongLineLimitMB <= 0 {
+		return nil, errors.New("--max-line-bytes and --long-line-limit-mb must be positive")
+	}
+	switch cfg.JSONArrays {
+	case "keep", "index":
+	default:
+		return nil, fmt.Errorf("invalid --json-arrays %q (want keep or index)", cfg.JSONArrays)
+	}
+	if cfg.JSONDepth < 0 {
+		return nil, errors.New("--json-depth cannot be negative")
+	}
+	if cfg.ReplaySpeed, err = ParseSpeed(replaySpeed); err != nil {
+		return nil, fmt.Errorf("invalid --replay-speed: %w", err)
+	}
+	if cfg.Replay && len(cfg.FilePaths) == 0 && cfg.Load == "" {
+		return nil, errors.New("--replay requires --file or --load")
+	}
+	if cfg.Replay && len(cfg.FilePaths) > 1 {
+		// Files are read side by side, so their records would not come in
+		// timestamp order
+		return nil, errors.New("--replay takes a single --file")
+	}
+	if cfg.Record != "" && cfg.Record == cfg.Load {
+		return nil, errors.New("--record and --load cannot be the same file")
+	}
+	if cfg.Follow && !cfg.Until.IsZero() {
+		return nil, errors.New("--until cannot be combined with --follow")
+	}
+
 	if cfg.ExportFormat != "" && cfg.ExportOut == "" {
 		return nil, errors.New("--export requires --out path")
 	}
 
 	// Determine input source defaults
-	if cfg.UseStdin || (cfg.IsPipedStdin && cfg.FilePath == "") {
+	if cfg.UseStdin || (cfg.IsPipedStdin && !cfg.HasFileInput() && !cfg.HasOtherInput()) {
 		cfg.UseStdin = true
 	}
 
-	if !cfg.UseStdin && cfg.FilePath == "" {
+	if !cfg.UseStdin && !cfg.HasFileInput() && !cfg.HasOtherInput() {
 		// No input: will run demo mode
 	}
 
@@ -102,6 +302,130 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// timeBoundLayouts are the absolute formats accepted by ParseTimeBound.
+// Layouts without a zone are read in local time.
+var timeBoundLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseTimeBound parses a --since/--until style time: an absolute date and
+// time, a time of day ("14:02", "14:02:30") meaning today, or a duration
+// ("30m", "2h") meaning that long before now.
+func ParseTimeBound(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeBoundLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			y, m, d := now.Date()
+			return time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
+		}
+	}
+	if d, err := time.ParseDuration(strings.TrimPrefix(s, "-")); err == nil {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time %q", s)
+}
+
+// stringList is a repeatable string flag.
+type stringList []string
+
+func (s *stringList) String() string { return strings.Join(*s, ",") }
+
+func (s *stringList) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// ParseSpeed parses a speed multiplier such as "10x", "0.5x" or "2".
+func ParseSpeed(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "x"), 64)
+	if err != nil {
+		return 0, err
+	}
+	if v <= 0 {
+		return 0, fmt.Errorf("speed must be positive, got %q", s)
+	}
+	return v, nil
+}
+
+// expandGlobs resolves glob patterns into concrete paths, keeping order and
+// dropping duplicates. Plain paths are kept as-is so a missing file surfaces
+// as an ingest error instead of being silently ignored.
+func expandGlobs(patterns []string) ([]string, error) {
+	out := []string{}
+	seen := map[string]bool{}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		matches := []string{p}
+		if strings.ContainsAny(p, "*?[") {
+			m, err := filepath.Glob(p)
+			if err != nil {
+				return nil, fmt.Errorf("invalid glob %q: %w", p, err)
+			}
+			if len(m) == 0 {
+				return nil, fmt.Errorf("no files match %q", p)
+			}
+			matches = m
+		}
+		for _, f := range matches {
+			if seen[f] {
+				continue
+			}
+			seen[f] = true
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+// HasFileInput reports whether a file-based source was requested.
+func (c *Config) HasFileInput() bool {
+	return len(c.FilePaths) > 0 || c.RotatedBase != "" || c.Dir != ""
+}
+
+// HasOtherInput reports whether a non-file, non-stdin source was requested.
+func (c *Config) HasOtherInput() bool {
+	return c.Command != "" || c.ListenSyslog != "" || c.ListenHTTP != "" || c.ListenOTLP != "" || c.Load != ""
+}
+
+// PrimaryFile returns the first input file, used to key per-file caches.
+func (c *Config) PrimaryFile() string {
+	if c.RotatedBase != "" {
+		return c.RotatedBase
+	}
+	if c.Dir != "" {
+		return c.Dir
+	}
+	if c.Load != "" {
+		return c.Load
+	}
+	if len(c.FilePaths) == 0 {
+		return ""
+	}
+	return c.FilePaths[0]
+}
+
+// LineLimit returns the longest line, in bytes, passed on whole; longer
+// lines are truncated to it.
+func (c *Config) LineLimit() int {
+	if c.LongLines == "keep" {
+		return c.LongLineLimitMB * 1024 * 1024
+	}
+	return c.MaxLineBytes
+}
+
 func getenvDefault(k, d string) string {
 	if v := os.Getenv(k); v != "" {
 		return v
@@ -121,5 +445,26 @@ func getenvDefaultInt(k string, d int) int {
 func (c *Config) OpenAIKey() string { return os.Getenv("OPENAI_API_KEY") }
 
 func (c *Config) String() string {
-	return fmt.Sprintf("file=%s stdin=%v follow=%v theme=%s offline=%v", c.FilePath, c.UseStdin, c.Follow, c.Theme, c.Offline)
+	if c.ListenOTLP != "" {
+		return fmt.Sprintf("listen-otlp=%s theme=%s offline=%v", c.ListenOTLP, c.Theme, c.Offline)
+	}
+	if c.ListenHTTP != "" {
+		return fmt.Sprintf("listen-http=%s theme=%s offline=%v", c.ListenHTTP, c.Theme, c.Offline)
+	}
+	if c.ListenSyslog != "" {
+		return fmt.Sprintf("listen-syslog=%s theme=%s offline=%v", c.ListenSyslog, c.Theme, c.Offline)
+	}
+	if c.Command != "" {
+		return fmt.Sprintf("cmd=%q follow=%v theme=%s offline=%v", c.Command, c.Follow, c.Theme, c.Offline)
+	}
+	if c.Load != "" {
+		return fmt.Sprintf("load=%s replay=%v theme=%s offline=%v", c.Load, c.Replay, c.Theme, c.Offline)
+	}
+	if c.Dir != "" {
+		return fmt.Sprintf("dir=%s include=%s follow=%v theme=%s offline=%v", c.Dir, c.Include, c.Follow, c.Theme, c.Offline)
+	}
+	if c.RotatedBase != "" {
+		return fmt.Sprintf("rotated=%s stdin=%v follow=%v theme=%s offline=%v", c.RotatedBase, c.UseStdin, c.Follow, c.Theme, c.Offline)
+	}
+	return fmt.Sprintf("file=%s stdin=%v follow=%v theme=%s offline=%v", strings.Join(c.FilePaths, ","), c.UseStdin, c.Follow, c.Theme, c.Offline)
 }
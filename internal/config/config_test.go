@@ -0,0 +1,75 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandGlobs(t *testing.T) {
+	dir := t.TempDir()
+	for _, n := range []string{"a.log", "b.log", "c.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, n), []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	a := filepath.Join(dir, "a.log")
+	got, err := expandGlobs([]string{a, filepath.Join(dir, "*.log"), "/no/such/plain.log"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Duplicates are dropped and plain paths are kept even if missing
+	want := []string{a, filepath.Join(dir, "b.log"), "/no/such/plain.log"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	if _, err := expandGlobs([]string{filepath.Join(dir, "*.gz")}); err == nil {
+		t.Fatalf("a glob matching nothing should fail")
+	}
+}
+
+func TestLoadRepeatableFile(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	os.WriteFile(a, []byte("x\n"), 0o644)
+	os.WriteFile(b, []byte("x\n"), 0o644)
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"logsense", "--offline", "--file", a, "--file", filepath.Join(dir, "b.*")}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.FilePaths) != 2 || cfg.FilePaths[0] != a || cfg.FilePaths[1] != b {
+		t.Fatalf("paths: %v", cfg.FilePaths)
+	}
+}
+
+func TestLoadGrokPatternsStayInConfig(t *testing.T) {
+	dir := t.TempDir()
+	pats := filepath.Join(dir, "app")
+	os.WriteFile(pats, []byte("APPID app-[0-9]+\n"), 0o644)
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"logsense", "--offline", "--grok-patterns", pats, "--regex-pattern", "%{APPID:app} %{GREEDYDATA:msg}"}
+	cfg, err := Load()
+	if err != nil || cfg.Grok == nil {
+		t.Fatalf("load: %v", err)
+	}
+	// Loading patterns for one config does not make them known to the next
+	os.Args = []string{"logsense", "--offline", "--regex-pattern", "%{APPID:app} %{GREEDYDATA:msg}"}
+	if _, err := Load(); err == nil {
+		t.Fatalf("pattern from an earlier Load was still known")
+	}
+}
+
+func TestReplayTakesOneFile(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	os.WriteFile(a, []byte("x\n"), 0o644)
+	os.WriteFile(b, []byte("x\n"), 0o644)
+	defer func(args []string) { os.Args = args }(os.Args)
+	os.Args = []string{"logsense", "--offline", "--replay", "--file", a, "--file", b}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "single --file") {
+		t.Fatalf("err = %v", err)
+	}
+}
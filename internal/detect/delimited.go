@@ -0,0 +1,232 @@
+package detect
+
+import (
+	"encoding/csv"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"logsense/internal/model"
+	"logsense/internal/parse"
+)
+
+// delimiters are the separators tried by Delimited, in order of preference.
+var delimiters = []rune{',', '\t', ';', '|'}
+
+var reHeaderName = regexp.MustCompile(`^[A-Za-z_@][A-Za-z0-9_ .@/()-]{0,63}$`)
+
+// csvTimeLayouts are the timestamp layouts recognized in delimited logs.
+var csvTimeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02 15:04:05.999999999Z07:00", "2006/01/02 15:04:05", "02/01/2006 15:04:05", time.Stamp}
+
+// Delimited recognizes CSV/TSV-like logs: every line (but the odd one) splits
+// into the same number (at least 3) of fields on one delimiter, and either
+// the first row is a header naming the columns or a timestamp or level
+// column is found by its values (the rest are then col<N>). Prose with a
+// couple of commas per line has neither.
+func Delimited(sample []string) (Guess, bool) {
+	return delimited(sample, true)
+}
+
+// ForcedDelimited is Delimited for --format=csv: the stream is known to be a
+// table, so neither a header nor a time or level column is required.
+func ForcedDelimited(sample []string) (Guess, bool) {
+	return delimited(sample, false)
+}
+
+func delimited(sample []string, strict bool) (Guess, bool) {
+	lines := []string{}
+	for _, l := range sample {
+		s := strings.TrimSpace(l)
+		if s == "" {
+			continue
+		}
+		// JSON has commas everywhere and access logs have user agents full of
+		// ';' without being tables
+		if strings.HasPrefix(s, "{") || strings.HasPrefix(s, "[") || reApacheCombined.MatchString(s) || reSyslogRFC5424.MatchString(s) || reSyslogRFC3164.MatchString(s) {
+			return Guess{}, false
+		}
+		lines = append(lines, l)
+	}
+	if len(lines) < 2 {
+		return Guess{}, false
+	}
+	for _, d := range delimiters {
+		rows, n := splitRows(lines, d)
+		if n < 3 || keyValueRows(rows) || (strict && !isHeaderRow(rows) && !hasTimeOrLevelColumn(rows)) {
+			continue
+		}
+		return Guess{Schema: delimitedSchema(rows, n, d), Confidence: conf(len(lines), len(rows))}, true
+	}
+	return Guess{}, false
+}
+
+// splitRows parses lines with delimiter d and returns the rows having the
+// most common field count n, or n = 0 when fewer than 90% of lines agree.
+func splitRows(lines []string, d rune) ([][]string, int) {
+	all := make([][]string, 0, len(lines))
+	counts := map[int]int{}
+	for _, l := range lines {
+		r := csv.NewReader(strings.NewReader(l))
+		r.Comma = d
+		r.LazyQuotes = true
+		r.FieldsPerRecord = -1
+		r.TrimLeadingSpace = d != '\t'
+		rec, err := r.Read()
+		if err != nil {
+			rec = nil
+		}
+		all = append(all, rec)
+		counts[len(rec)]++
+	}
+	n := 0
+	for c, k := range counts {
+		if k > counts[n] || (k == counts[n] && c > n) {
+			n = c
+		}
+	}
+	if counts[n]*10 < len(lines)*9 {
+		return nil, 0
+	}
+	rows := make([][]string, 0, counts[n])
+	for _, rec := range all {
+		if len(rec) == n {
+			rows = append(rows, rec)
+		}
+	}
+	return rows, n
+}
+
+// keyValueRows reports whether most rows start with key=value, i.e. logfmt
+// whose values happen to hold the delimiter.
+func keyValueRows(rows [][]string) bool {
+	kv := 0
+	for _, r := range rows {
+		if reLogfmtKV.MatchString(r[0]) {
+			kv++
+		}
+	}
+	return kv*2 > len(rows)
+}
+
+func delimitedSchema(rows [][]string, n int, d rune) model.Schema {
+	name := "csv"
+	if d == '\t' {
+		name = "tsv"
+	}
+	s := model.Schema{FormatName: name, ParseStrategy: "csv", Delimiter: string(d), LevelMapping: map[string]string{}, Confidence: 0.7}
+	names := make([]string, n)
+	data := rows
+	if isHeaderRow(rows) {
+		copy(names, rows[0])
+		data = rows[1:]
+		s.HeaderRow = true
+	}
+	// Without a header (or for unnamed header cells) name the timestamp and
+	// level columns by their content
+	for i := range names {
+		names[i] = strings.TrimSpace(names[i])
+		if names[i] != "" {
+			continue
+		}
+		switch {
+		case !hasName(names, "ts") && columnLayout(data, i) != "":
+			names[i] = "ts"
+		case !hasName(names, "level") && isLevelColumn(data, i):
+			names[i] = "level"
+		default:
+			names[i] = fmt.Sprintf("col%d", i+1)
+		}
+	}
+	for i, col := range names {
+		if hasName(names[:i], col) {
+			col = fmt.Sprintf("%s_%d", col, i+1)
+			names[i] = col
+		}
+		s.Fields = append(s.Fields, model.FieldDef{Name: col, Type: "string", Description: "column " + strconv.Itoa(i+1), PathOrGroup: col})
+		if s.TimeLayout == "" && parse.IsTimeColumn(col) {
+			s.TimeLayout = columnLayout(data, i)
+		}
+	}
+	return s
+}
+
+// isHeaderRow reports whether the first row names the columns: its cells
+// look like identifiers, not numbers, and none of them shows up again in
+// its column.
+func isHeaderRow(rows [][]string) bool {
+	if len(rows) < 2 {
+		return false
+	}
+	for i, h := range rows[0] {
+		h = strings.TrimSpace(h)
+		if !reHeaderName.MatchString(h) {
+			return false
+		}
+		if _, err := strconv.ParseFloat(h, 64); err == nil {
+			return false
+		}
+		for _, r := range rows[1:] {
+			if strings.TrimSpace(r[i]) == h {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// columnLayout returns the time layout every value of column i parses with,
+// or "".
+func columnLayout(rows [][]string, i int) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	for _, layout := range csvTimeLayouts {
+		ok := true
+		for _, r := range rows {
+			if _, err := time.Parse(layout, strings.TrimSpace(r[i])); err != nil {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return layout
+		}
+	}
+	return ""
+}
+
+// hasTimeOrLevelColumn reports whether some column of rows holds only
+// timestamps or only level names.
+func hasTimeOrLevelColumn(rows [][]string) bool {
+	for i := range rows[0] {
+		if columnLayout(rows, i) != "" || isLevelColumn(rows, i) {
+			return true
+		}
+	}
+	return false
+}
+
+func isLevelColumn(rows [][]string, i int) bool {
+	if len(rows) == 0 {
+		return false
+	}
+	for _, r := range rows {
+		switch strings.ToUpper(strings.TrimSpace(r[i])) {
+		case "TRACE", "DEBUG", "INFO", "WARN", "WARNING", "ERROR", "ERR", "FATAL", "CRITICAL":
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func hasName(names []string, n string) bool {
+	for _, x := range names {
+		if x == n {
+			return true
+		}
+	}
+	return false
+}
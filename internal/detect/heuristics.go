@@ -2,15 +2,18 @@ package detect
 
 import (
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
 	"logsense/internal/model"
+	"logsense/internal/parse"
 )
 
 var (
 	reApacheCombined = regexp.MustCompile(`^\S+ \S+ \S+ \[[^\]]+\] "[A-Z]+ [^\s]+ [^"]+" \d{3} \d+ "[^"]*" "[^"]*"`)
 	reSyslogRFC5424  = regexp.MustCompile(`^<\d+>1 \d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}`)
+	reSyslogRFC3164  = regexp.MustCompile(`^(?:<\d{1,3}>)?[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2} \S+ [^:\s]+:`)
 	reLogfmtKV       = regexp.MustCompile(`[a-zA-Z_][a-zA-Z0-9_]*=`)
 )
 
@@ -21,11 +24,21 @@ type Guess struct {
 
 // Quick offline heuristics on a small sample.
 func Heuristics(sample []string) Guess {
+	if g, ok := criHeuristics(sample); ok {
+		return g
+	}
+	if g, ok := Delimited(sample); ok {
+		return g
+	}
+	if g, ok := mixedHeuristics(sample); ok {
+		return g
+	}
 	lines := 0
 	jsonCount := 0
 	logfmtCount := 0
 	apacheCount := 0
 	syslogCount := 0
+	bsdCount := 0
 	for _, l := range sample {
 		s := strings.TrimSpace(l)
 		if s == "" {
@@ -44,24 +57,131 @@ func Heuristics(sample []string) Guess {
 		if reSyslogRFC5424.MatchString(s) {
 			syslogCount++
 		}
+		if reSyslogRFC3164.MatchString(s) {
+			bsdCount++
+		}
 	}
 	// Choose highest
 	if jsonCount > logfmtCount && jsonCount > apacheCount && jsonCount > syslogCount && jsonCount >= lines/2 {
 		return Guess{Schema: jsonSchema(), Confidence: conf(lines, jsonCount)}
 	}
-	if logfmtCount >= apacheCount && logfmtCount >= syslogCount && logfmtCount >= lines/2 {
+	if logfmtCount >= apacheCount && logfmtCount >= syslogCount && logfmtCount >= bsdCount && logfmtCount >= lines/2 {
 		return Guess{Schema: logfmtSchema(), Confidence: conf(lines, logfmtCount)}
 	}
-	if apacheCount >= syslogCount && apacheCount > 0 {
+	if apacheCount >= syslogCount && apacheCount >= bsdCount && apacheCount > 0 {
 		return Guess{Schema: apacheSchema(), Confidence: conf(lines, apacheCount)}
 	}
-	if syslogCount > 0 {
+	if syslogCount >= bsdCount && syslogCount > 0 {
 		return Guess{Schema: syslogSchema(), Confidence: conf(lines, syslogCount)}
 	}
+	if bsdCount > 0 {
+		return Guess{Schema: syslogRFC3164Schema(), Confidence: conf(lines, bsdCount)}
+	}
 	// Unknown
 	return Guess{Schema: unknownSchema(), Confidence: 0.0}
 }
 
+// mixedMinShare is the share of the sample a second format must reach for
+// the stream to be treated as mixed rather than as one format with noise.
+const mixedMinShare = 0.1
+
+// mixedHeuristics recognizes streams that interleave several formats (e.g.
+// an app writing JSON next to a proxy writing access logs) and returns a
+// "mixed" schema with one variant per format, most frequent first.
+func mixedHeuristics(sample []string) (Guess, bool) {
+	kinds := []struct {
+		schema func() model.Schema
+		count  int
+	}{{schema: jsonSchema}, {schema: apacheSchema}, {schema: syslogSchema}, {schema: syslogRFC3164Schema}, {schema: logfmtSchema}}
+	lines, classified := 0, 0
+	for _, l := range sample {
+		s := strings.TrimSpace(l)
+		if s == "" {
+			continue
+		}
+		lines++
+		// Each line counts once, for the most specific format it matches
+		k := -1
+		switch {
+		case strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}"):
+			k = 0
+		case reApacheCombined.MatchString(s):
+			k = 1
+		case reSyslogRFC5424.MatchString(s):
+			k = 2
+		case reSyslogRFC3164.MatchString(s):
+			k = 3
+		case reLogfmtKV.MatchString(s):
+			k = 4
+		}
+		if k >= 0 {
+			kinds[k].count++
+			classified++
+		}
+	}
+	sort.SliceStable(kinds, func(i, j int) bool { return kinds[i].count > kinds[j].count })
+	need := max(1, int(mixedMinShare*float64(lines)+0.5))
+	if kinds[1].count < need {
+		return Guess{}, false
+	}
+	s := model.Schema{FormatName: "mixed", ParseStrategy: "mixed", LevelMapping: map[string]string{}}
+	for _, k := range kinds {
+		if k.count < need {
+			break
+		}
+		v := k.schema()
+		s.Variants = append(s.Variants, v)
+		for _, f := range v.Fields {
+			if !hasField(s.Fields, f.Name) {
+				s.Fields = append(s.Fields, f)
+			}
+		}
+	}
+	s.Confidence = conf(lines, classified)
+	return Guess{Schema: s, Confidence: s.Confidence}, true
+}
+
+// criHeuristics recognizes Kubernetes CRI container logs, strips the envelope
+// and detects the format of the payloads underneath.
+func criHeuristics(sample []string) (Guess, bool) {
+	lines := 0
+	payloads := []string{}
+	for _, l := range sample {
+		if strings.TrimSpace(l) == "" {
+			continue
+		}
+		lines++
+		if cl, ok := parse.SplitCRI(l); ok {
+			payloads = append(payloads, cl.Payload)
+		}
+	}
+	if len(payloads) == 0 || len(payloads) < lines/2 {
+		return Guess{}, false
+	}
+	inner := Heuristics(payloads)
+	s := inner.Schema
+	s.Envelope = "cri"
+	s.FormatName = "cri/" + s.FormatName
+	s.Fields = append(append([]model.FieldDef{}, s.Fields...), model.FieldDef{Name: "stream", Type: "string", Description: "container stream", PathOrGroup: "stream"})
+	if !hasField(s.Fields, "ts") {
+		s.Fields = append(s.Fields, model.FieldDef{Name: "ts", Type: "string", Description: "timestamp", PathOrGroup: "ts"})
+	}
+	c := inner.Confidence
+	if c == 0 {
+		c = conf(lines, len(payloads)) / 2
+	}
+	return Guess{Schema: s, Confidence: c}, true
+}
+
+func hasField(fields []model.FieldDef, name string) bool {
+	for _, f := range fields {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func conf(lines, hits int) float64 {
 	if lines == 0 {
 		return 0
@@ -115,6 +235,20 @@ func syslogSchema() model.Schema {
 	}
 }
 
+// syslogRFC3164Schema covers BSD syslog as sent over the network (with PRI)
+// and as written to /var/log/syslog or /var/log/messages (without PRI).
+func syslogRFC3164Schema() model.Schema {
+	return model.Schema{
+		FormatName:    "syslog_rfc3164",
+		ParseStrategy: "regex",
+		RegexPattern:  `^(?:<(?P<pri>\d{1,3})>)?(?P<ts>[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}) (?P<host>\S+) (?P<app>[^:\[\s]+)(?:\[(?P<pid>\d+)\])?: ?(?P<msg>.*)$`,
+		TimeLayout:    time.Stamp,
+		LevelMapping:  map[string]string{},
+		Fields:        []model.FieldDef{{Name: "ts", Type: "string", Description: "timestamp", PathOrGroup: "ts"}, {Name: "host", Type: "string", Description: "host", PathOrGroup: "host"}, {Name: "app", Type: "string", Description: "app", PathOrGroup: "app"}, {Name: "msg", Type: "string", Description: "message", PathOrGroup: "msg"}},
+		Confidence:    0.6,
+	}
+}
+
 func unknownSchema() model.Schema {
 	return model.Schema{FormatName: "unknown", ParseStrategy: "regex", RegexPattern: `^(?P<msg>.*)$`, Fields: []model.FieldDef{{Name: "msg", Type: "string", Description: "message", PathOrGroup: "msg"}}}
 }
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"os"
 	"testing"
+
+	"logsense/internal/parse"
 )
 
 func readLines(path string, n int) []string {
@@ -36,3 +38,84 @@ func TestHeuristicsLogfmt(t *testing.T) {
 		t.Fatalf("expected logfmt, got %s", g.Schema.FormatName)
 	}
 }
+
+func TestHeuristicsSyslogRFC3164(t *testing.T) {
+	g := Heuristics([]string{
+		"<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8",
+		"Jan  1 12:00:01 host sshd[42]: Accepted publickey for root",
+	})
+	if g.Schema.FormatName != "syslog_rfc3164" {
+		t.Fatalf("expected syslog_rfc3164, got %s", g.Schema.FormatName)
+	}
+}
+
+func TestHeuristicsCRI(t *testing.T) {
+	g := Heuristics(readLines("../../testdata/k8s_cri.log", 10))
+	if g.Schema.FormatName != "cri/json_lines" || g.Schema.Envelope != "cri" || g.Schema.ParseStrategy != "json" {
+		t.Fatalf("expected cri/json_lines, got %s (%s)", g.Schema.FormatName, g.Schema.ParseStrategy)
+	}
+}
+
+func TestHeuristicsMixed(t *testing.T) {
+	g := Heuristics([]string{
+		`{"ts":"2025-01-01T12:00:00Z","level":"info","service":"api","msg":"server started","port":8080}`,
+		`time=2025-01-01T12:00:01Z level=warn user_id=42 msg="slow request" path=/v1/items lat_ms=512`,
+		`127.0.0.1 - - [01/Jan/2025:12:00:02 +0000] "GET /index.html HTTP/1.1" 200 1234 "-" "curl/8.0"`,
+		`{"ts":"2025-01-01T12:00:03Z","level":"error","msg":"db timeout"}`,
+	})
+	s := g.Schema
+	if s.ParseStrategy != "mixed" || len(s.Variants) != 3 || s.Variants[0].FormatName != "json_lines" {
+		t.Fatalf("expected mixed with json_lines first, got %s %+v", s.ParseStrategy, s.Variants)
+	}
+	if !hasField(s.Fields, "status") || !hasField(s.Fields, "level") {
+		t.Fatalf("expected union of variant fields, got %+v", s.Fields)
+	}
+	// A single odd line in a large sample is noise, not a second format
+	sample := readLines("../../testdata/json_lines.ndjson", 10)
+	for len(sample) < 20 {
+		sample = append(sample, sample...)
+	}
+	sample = append(sample, "time=2025-01-01T12:00:01Z level=warn msg=odd")
+	if g := Heuristics(sample); g.Schema.FormatName != "json_lines" {
+		t.Fatalf("expected json_lines, got %s", g.Schema.FormatName)
+	}
+}
+
+func TestHeuristicsCSV(t *testing.T) {
+	g := Heuristics(readLines("../../testdata/db_audit.csv", 10))
+	s := g.Schema
+	if s.ParseStrategy != "csv" || s.Delimiter != "," || len(s.Fields) != 8 || s.Fields[0].Name != "event_time" || s.Fields[7].Name != "statement" {
+		t.Fatalf("expected csv with header columns, got %s %q %+v", s.ParseStrategy, s.Delimiter, s.Fields)
+	}
+	if s.TimeLayout == "" {
+		t.Fatalf("expected a time layout for event_time")
+	}
+	// The header row names the columns and is not ingested as a record
+	lines := readLines("../../testdata/db_audit.csv", 2)
+	p, _ := parse.NewParser(s, "", parse.Options{})
+	if e := p.Parse(lines[0], "audit"); !s.HeaderRow || !e.Header {
+		t.Fatalf("header row parsed as a record: %+v", e)
+	}
+	if e := p.Parse(lines[1], "audit"); e.Header || e.Timestamp == nil || e.Fields["user"] != "alice" {
+		t.Fatalf("first record: %+v", e)
+	}
+	// Without a header the timestamp and level columns are found by content
+	g = Heuristics([]string{
+		"2025-01-01T12:00:00Z\tINFO\tbatch-7\tjob started",
+		"2025-01-01T12:00:05Z\tWARN\tbatch-7\tretrying, attempt 2",
+		"2025-01-01T12:01:00Z\tERROR\tbatch-7\tjob failed",
+	})
+	s = g.Schema
+	if s.FormatName != "tsv" || len(s.Fields) != 4 || s.Fields[0].Name != "ts" || s.Fields[1].Name != "level" || s.Fields[2].Name != "col3" {
+		t.Fatalf("expected tsv with ts/level columns, got %s %+v", s.FormatName, s.Fields)
+	}
+	// Prose with a couple of commas per line is not a table
+	g = Heuristics([]string{
+		"Listening on :8080, tls off, workers 4",
+		"Connected to db, pool 10, timeout 5s",
+		"Cache warmed, 1200 keys, took 3s",
+	})
+	if g.Schema.ParseStrategy == "csv" {
+		t.Fatalf("prose detected as %s %+v", g.Schema.FormatName, g.Schema.Fields)
+	}
+}
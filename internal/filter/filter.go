@@ -61,6 +61,8 @@ func (e *Evaluator) Match(entry model.LogEntry, c Criteria) bool {
 					b, _ := json.Marshal(v)
 					text = string(b)
 				}
+			} else if c.Field == "source" {
+				text = entry.Source
 			} else {
 				text = ""
 			}
@@ -82,6 +84,9 @@ func (e *Evaluator) Match(entry model.LogEntry, c Criteria) bool {
 		}
 		// also add common fields
 		params["level"] = entry.Level
+		if _, ok := params["source"]; !ok {
+			params["source"] = entry.Source
+		}
 		if entry.Timestamp != nil {
 			params["ts"] = entry.Timestamp.Format("2006-01-02T15:04:05Z07:00")
 		}
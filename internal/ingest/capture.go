@@ -0,0 +1,242 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"logsense/internal/util/logx"
+)
+
+// captureMagic starts every capture file, followed by its records:
+//
+//	uvarint source  index into the sources seen so far; the next unused
+//	                index introduces a new source, followed by uvarint
+//	                length + bytes of its name
+//	varint  when    nanoseconds since the previous record (Unix time for
+//	                the first one)
+//	uvarint length  + bytes of the line text
+//
+// Captures are read through openInput, so they may be gzip/zstd compressed.
+var captureMagic = []byte("LSR\x01")
+
+// maxCaptureRecord is the longest name or line text a capture holds; longer
+// lines are cut to it when recorded, so a longer length means a corrupt file.
+const maxCaptureRecord = 1 << 30
+
+// Recorder writes the raw lines of a session to a capture file that can be
+// reopened later with SourceCapture. It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	f       *os.File
+	w       *bufio.Writer
+	sources map[string]uint64
+	last    int64
+	buf     []byte
+	err     error
+}
+
+// CreateRecorder creates (or truncates) the capture file at path.
+func CreateRecorder(path string) (*Recorder, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Recorder{f: f, w: bufio.NewWriterSize(f, 64<<10), sources: map[string]uint64{}}
+	if _, err := r.w.Write(captureMagic); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record appends one line. After the first write error every call returns
+// that error.
+func (r *Recorder) Record(l Line) error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	b := r.buf[:0]
+	idx, ok := r.sources[l.Source]
+	if !ok {
+		idx = uint64(len(r.sources))
+		r.sources[l.Source] = idx
+		b = binary.AppendUvarint(b, idx)
+		b = binary.AppendUvarint(b, uint64(len(l.Source)))
+		b = append(b, l.Source...)
+	} else {
+		b = binary.AppendUvarint(b, idx)
+	}
+	when := l.When.UnixNano()
+	b = binary.AppendVarint(b, when-r.last)
+	r.last = when
+	text := cutText(l.Text, maxCaptureRecord)
+	b = binary.AppendUvarint(b, uint64(len(text)))
+	b = append(b, text...)
+	r.buf = b
+	_, r.err = r.w.Write(b)
+	return r.err
+}
+
+// Flush writes buffered records to the file.
+func (r *Recorder) Flush() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return r.err
+	}
+	r.err = r.w.Flush()
+	return r.err
+}
+
+// Close flushes and closes the capture file.
+func (r *Recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	err := r.Flush()
+	if cerr := r.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// record writes every input line passing through to rec (reader notices
+// are not input), flushing whenever the input is idle so an abrupt exit
+// loses little.
+func record(ctx context.Context, in <-chan Line, rec *Recorder) <-chan Line {
+	out := make(chan Line, cap(in))
+	go func() {
+		defer close(out)
+		failed := false
+		for l := range in {
+			if !failed && !l.Marker {
+				err := rec.Record(l)
+				if err == nil && len(in) == 0 {
+					err = rec.Flush()
+				}
+				if err != nil {
+					logx.Errorf("ingest: recording stopped: %v", err)
+					failed = true
+				}
+			}
+			select {
+			case out <- l:
+			case <-ctx.Done():
+				return
+			}
+		}
+		if !failed {
+			_ = rec.Flush()
+		}
+	}()
+	return out
+}
+
+// readCapture re-emits the lines of a capture file with their original
+// source tags and arrival times.
+func readCapture(ctx context.Context, path string, maxBuf int, out chan<- Line, errs chan<- error) {
+	rc, _, err := openInput(path)
+	if err != nil {
+		errs <- err
+		return
+	}
+	defer rc.Close()
+	br := bufio.NewReaderSize(rc, 64<<10)
+	magic := make([]byte, len(captureMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != string(captureMagic) {
+		errs <- fmt.Errorf("%s is not a logsense capture", path)
+		return
+	}
+	sources := []string{}
+	var when int64
+	if maxBuf <= 0 {
+		maxBuf = bufio.MaxScanTokenSize
+	}
+	// readText reads a length-prefixed string, cut to the current line
+	// limit (the capture may come from a session with a larger one); dropped
+	// is how many bytes were cut
+	readText := func() (text string, dropped int64, err error) {
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return "", 0, err
+		}
+		if n > maxCaptureRecord {
+			return "", 0, fmt.Errorf("record of %d bytes exceeds the capture limit", n)
+		}
+		// One byte past the limit tells whether the cut splits a rune
+		b := make([]byte, min(n, uint64(maxBuf)+1))
+		if _, err := io.ReadFull(br, b); err != nil {
+			return "", 0, err
+		}
+		if _, err := br.Discard(int(n - uint64(len(b)))); err != nil {
+			return "", 0, err
+		}
+		text = cutText(string(b), maxBuf)
+		return text, int64(n) - int64(len(text)), nil
+	}
+	for ctx.Err() == nil {
+		idx, err := binary.ReadUvarint(br)
+		if err == io.EOF {
+			return
+		}
+		if err == nil && idx == uint64(len(sources)) {
+			var name string
+			if name, _, err = readText(); err == nil {
+				sources = append(sources, name)
+			}
+		}
+		if err == nil && idx >= uint64(len(sources)) {
+			err = errors.New("bad source index")
+		}
+		var delta int64
+		if err == nil {
+			delta, err = binary.ReadVarint(br)
+		}
+		var text string
+		var dropped int64
+		if err == nil {
+			text, dropped, err = readText()
+		}
+		if err != nil {
+			// A capture cut short by an abrupt exit ends with a partial record
+			if err != io.ErrUnexpectedEOF && err != io.EOF {
+				errs <- fmt.Errorf("capture %s: %w", path, err)
+			}
+			return
+		}
+		when += delta
+		select {
+		case out <- Line{Text: text, Source: sources[idx], When: time.Unix(0, when), Truncated: dropped}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// cutText cuts s to at most max bytes without splitting a UTF-8 sequence.
+func cutText(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	kept := max
+	for i := 1; i < utf8.UTFMax && kept > 0 && !utf8.RuneStart(s[kept]); i++ {
+		kept--
+	}
+	return s[:kept]
+}
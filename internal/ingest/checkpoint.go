@@ -0,0 +1,181 @@
+package ingest
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"logsense/internal/util/logx"
+)
+
+// Checkpoint records how far a file was read in a previous session.
+type Checkpoint struct {
+	Path   string    `json:"path"`
+	Offset int64     `json:"offset"` // bytes consumed
+	Inode  uint64    `json:"inode"`  // 0 when the platform has no inodes
+	Size   int64     `json:"size"`   // file size when saved
+	Saved  time.Time `json:"saved"`
+}
+
+// checkpointDir returns a directory under the OS temp dir to store read
+// checkpoints.
+func checkpointDir() string {
+	return filepath.Join(os.TempDir(), "logsense-checkpoints")
+}
+
+// checkpointKey derives a stable key from the absolute file path.
+func checkpointKey(filePath string) (string, string, error) {
+	if strings.TrimSpace(filePath) == "" {
+		return "", "", errors.New("empty path")
+	}
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", "", err
+	}
+	h := sha1.Sum([]byte(abs))
+	return hex.EncodeToString(h[:]), abs, nil
+}
+
+// LoadCheckpoint reads the saved checkpoint of a file path.
+func LoadCheckpoint(filePath string) (Checkpoint, bool) {
+	key, _, err := checkpointKey(filePath)
+	if err != nil {
+		return Checkpoint{}, false
+	}
+	p := filepath.Join(checkpointDir(), fmt.Sprintf("checkpoint_%s.json", key))
+	f, err := os.Open(p)
+	if err != nil {
+		return Checkpoint{}, false
+	}
+	defer f.Close()
+	var cp Checkpoint
+	if err := json.NewDecoder(f).Decode(&cp); err != nil {
+		return Checkpoint{}, false
+	}
+	return cp, true
+}
+
+// SaveCheckpoint records that filePath was consumed up to offset. The inode
+// and size of the file are taken now so a later session can tell whether it
+// was rotated or truncated in between.
+func SaveCheckpoint(filePath string, offset int64) error {
+	key, abs, err := checkpointKey(filePath)
+	if err != nil {
+		return err
+	}
+	st, err := os.Stat(abs)
+	if err != nil {
+		return err
+	}
+	cp := Checkpoint{Path: abs, Offset: offset, Inode: fileInode(st), Size: st.Size(), Saved: time.Now()}
+	dir := checkpointDir()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	p := filepath.Join(dir, fmt.Sprintf("checkpoint_%s.json", key))
+	tmp := p + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(cp); err != nil {
+		f.Close()
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, p); err != nil {
+		return err
+	}
+	logx.Debugf("ingest: checkpoint of %s saved at offset %d", abs, offset)
+	return nil
+}
+
+// resumePlan works out where to continue reading path from its checkpoint.
+// When the file was rotated since, the rest of the old file is found among
+// its siblings by inode (rotated is empty when it is gone) and the live file
+// is read from the start; a truncated file is also read from the start.
+func resumePlan(path string, cp Checkpoint) (rotated string, start int64) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return "", 0
+	}
+	if sameFile(cp, st) {
+		if st.Size() < cp.Offset {
+			logx.Infof("ingest: %s was truncated since the last session; reading from the start", path)
+			return "", 0
+		}
+		return "", cp.Offset
+	}
+	logx.Infof("ingest: %s was rotated since the last session", path)
+	siblings, _ := rotatedSiblings(path)
+	for _, p := range siblings {
+		if sst, err := os.Stat(p); err == nil && cp.Inode != 0 && fileInode(sst) == cp.Inode && sst.Size() >= cp.Offset && !isCompressed(p) {
+			return p, cp.Offset
+		}
+	}
+	return "", 0
+}
+
+// sameFile reports whether st is the file described by cp. Without inodes
+// a file that shrank is assumed to be a new one.
+func sameFile(cp Checkpoint, st os.FileInfo) bool {
+	if ino := fileInode(st); ino != 0 && cp.Inode != 0 {
+		return ino == cp.Inode
+	}
+	return st.Size() >= cp.Offset
+}
+
+// readSinceCheckpoint reads what was appended to path since its checkpoint
+// was saved, including the tail of the previous file when it was rotated.
+// Without a checkpoint the file is read as usual.
+func readSinceCheckpoint(ctx context.Context, path string, opt Options, out chan<- Line, errs chan<- error) {
+	cp, ok := LoadCheckpoint(path)
+	if !ok || isCompressed(path) {
+		logx.Infof("ingest: no checkpoint for %s; reading it as usual", path)
+		readFile(ctx, path, opt, -1, out, errs)
+		return
+	}
+	rotated, start := resumePlan(path, cp)
+	if rotated != "" {
+		if err := readFileFrom(ctx, rotated, cp.Offset, opt.ScanBufSize, out, errs); err != nil {
+			errs <- err
+		}
+		start = 0
+	}
+	logx.Infof("ingest: resuming %s at offset %d (checkpoint of %s)", path, start, cp.Saved.Format(time.RFC3339))
+	if opt.Follow {
+		readFromTail(ctx, path, path, start, true, opt.ScanBufSize, opt.State, out, errs)
+		return
+	}
+	if err := readFileFrom(ctx, path, start, opt.ScanBufSize, out, errs); err != nil {
+		errs <- err
+	}
+}
+
+// readFileFrom reads a plain file from offset to its current end.
+func readFileFrom(ctx context.Context, path string, offset int64, maxBuf int, out chan<- Line, errs chan<- error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	readFromReaderAt(ctx, f, path, offset, maxBuf, out, errs)
+	return nil
+}
@@ -0,0 +1,110 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"sync"
+	"time"
+)
+
+const (
+	cmdBackoffMin = time.Second
+	cmdBackoffMax = 30 * time.Second
+	// A run that lasts at least this long resets the restart backoff.
+	cmdStableRun = 10 * time.Second
+)
+
+// shellCommand wraps a command line in the platform shell.
+func shellCommand(ctx context.Context, line string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", line)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", line)
+}
+
+// readFromCommand runs line through the shell and streams its stdout and
+// stderr as separate sources. While the shared state asks for supervision,
+// the command is restarted with exponential backoff whenever it exits; an
+// exited command is started again as soon as supervision is switched on.
+func readFromCommand(ctx context.Context, line string, opt Options, out chan<- Line, errs chan<- error) {
+	backoff := cmdBackoffMin
+	for {
+		started := time.Now()
+		err := runCommandOnce(ctx, line, opt, out)
+		if ctx.Err() != nil {
+			opt.State.setProcess("stopped")
+			return
+		}
+		status := "exited"
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			status = fmt.Sprintf("exited (code %d)", exitErr.ExitCode())
+		} else if err != nil {
+			status = "failed"
+			errs <- err
+		}
+		if !opt.State.supervising() {
+			opt.State.setProcess(status)
+			if opt.State == nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-opt.State.superviseResumed():
+			}
+			opt.State.addRestart()
+			backoff = cmdBackoffMin
+			continue
+		}
+		if time.Since(started) >= cmdStableRun {
+			backoff = cmdBackoffMin
+		}
+		opt.State.setProcess(fmt.Sprintf("%s, restarting in %s", status, backoff))
+		select {
+		case <-ctx.Done():
+			opt.State.setProcess("stopped")
+			return
+		case <-time.After(backoff):
+		}
+		opt.State.addRestart()
+		backoff *= 2
+		if backoff > cmdBackoffMax {
+			backoff = cmdBackoffMax
+		}
+	}
+}
+
+func runCommandOnce(ctx context.Context, line string, opt Options, out chan<- Line) error {
+	cmd := shellCommand(ctx, line)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	opt.State.setProcess(fmt.Sprintf("running (pid %d)", cmd.Process.Pid))
+	// Scanner errors on the pipes are expected when the process dies; the
+	// exit status reported by Wait is what matters here.
+	discard := make(chan error, 2)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		readFromReader(ctx, stdout, "stdout", opt.ScanBufSize, out, discard)
+	}()
+	go func() {
+		defer wg.Done()
+		readFromReader(ctx, stderr, "stderr", opt.ScanBufSize, out, discard)
+	}()
+	wg.Wait()
+	return cmd.Wait()
+}
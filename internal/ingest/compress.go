@@ -0,0 +1,153 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+type compression string
+
+const (
+	compressionNone  compression = ""
+	compressionGzip  compression = "gzip"
+	compressionBzip2 compression = "bzip2"
+	compressionZstd  compression = "zstd"
+)
+
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicBzip2 = []byte("BZh")
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// detectCompression sniffs the leading bytes of a file. The extension is only
+// consulted when the header is too short to be conclusive.
+func detectCompression(head []byte, path string) compression {
+	switch {
+	case bytes.HasPrefix(head, magicGzip):
+		return compressionGzip
+	case bytes.HasPrefix(head, magicBzip2):
+		return compressionBzip2
+	case bytes.HasPrefix(head, magicZstd):
+		return compressionZstd
+	}
+	if len(head) >= len(magicZstd) {
+		return compressionNone
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".gzip":
+		return compressionGzip
+	case ".bz2":
+		return compressionBzip2
+	case ".zst", ".zstd":
+		return compressionZstd
+	}
+	return compressionNone
+}
+
+// compressedReadCloser closes both the decompressor and the underlying file.
+type compressedReadCloser struct {
+	io.Reader
+	closers []func() error
+}
+
+func (c *compressedReadCloser) Close() error {
+	var first error
+	for _, fn := range c.closers {
+		if err := fn(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// openInput opens path and transparently decompresses gzip, bzip2 and zstd
+// content. The returned compression is compressionNone for plain files.
+func openInput(path string) (io.ReadCloser, compression, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, compressionNone, err
+	}
+	br := bufio.NewReader(f)
+	head, _ := br.Peek(len(magicZstd))
+	kind := detectCompression(head, path)
+	switch kind {
+	case compressionGzip:
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, kind, err
+		}
+		return &compressedReadCloser{Reader: zr, closers: []func() error{zr.Close, f.Close}}, kind, nil
+	case compressionBzip2:
+		return &compressedReadCloser{Reader: bzip2.NewReader(br), closers: []func() error{f.Close}}, kind, nil
+	case compressionZstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, kind, err
+		}
+		return &compressedReadCloser{Reader: zr, closers: []func() error{func() error { zr.Close(); return nil }, f.Close}}, kind, nil
+	}
+	return &compressedReadCloser{Reader: br, closers: []func() error{f.Close}}, kind, nil
+}
+
+// isCompressed reports whether the file at path holds compressed content.
+func isCompressed(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, len(magicZstd))
+	n, _ := io.ReadFull(f, head)
+	return detectCompression(head[:n], path) != compressionNone
+}
+
+// readLastBytes streams r to the end and emits only the trailing lines whose
+// combined size fits in maxBytes. Used for compressed input, where seeking to
+// "the last N MB" is only possible on the decompressed output.
+func readLastBytes(ctx context.Context, r io.Reader, src string, maxBytes int64, maxBuf int, out chan<- Line, errs chan<- error) {
+	lr := newLineReader(r, maxBuf)
+	window := []Line{}
+	var size int64
+	for {
+		text, _, dropped, err := lr.next()
+		if err != nil {
+			if err != io.EOF {
+				errs <- err
+			}
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		window = append(window, Line{Text: text, Source: src, Truncated: dropped})
+		size += int64(len(text)) + 1
+		for size > maxBytes && len(window) > 0 {
+			size -= int64(len(window[0].Text)) + 1
+			window[0] = Line{}
+			window = window[1:]
+		}
+	}
+	for _, l := range window {
+		l.When = time.Now()
+		select {
+		case <-ctx.Done():
+			return
+		case out <- l:
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package ingest
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"logsense/internal/parse"
+)
+
+// criPending is a CRI record whose partial (P) chunks are still being joined.
+type criPending struct {
+	first     parse.CRILine
+	payload   strings.Builder
+	line      Line
+	end       int64
+	truncated int64
+	last      time.Time
+}
+
+// joinCRIPartials reassembles Kubernetes CRI lines split by the runtime: P
+// (partial) chunks are buffered per source and stream until the closing F
+// chunk arrives, then emitted as a single F line carrying the first chunk's
+// timestamp. A record still incomplete after timeout is emitted as is (P),
+// so the last record shows up in follow mode. Only sources whose first line
+// has a CRI envelope are joined; other sources pass through untouched.
+func joinCRIPartials(ctx context.Context, in <-chan Line, timeout time.Duration) <-chan Line {
+	out := make(chan Line, cap(in))
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	go func() {
+		defer close(out)
+		envelope := map[string]bool{}
+		pending := map[string]*criPending{}
+		emit := func(l Line) bool {
+			select {
+			case out <- l:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		flush := func(key string) bool {
+			p := pending[key]
+			delete(pending, key)
+			return emit(p.joined("P"))
+		}
+		ticker := time.NewTicker(max(timeout/2, 50*time.Millisecond))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				for key, p := range pending {
+					if now.Sub(p.last) >= timeout && !flush(key) {
+						return
+					}
+				}
+				continue
+			case l, ok := <-in:
+				if !ok {
+					// Input ended mid-record: emit what was collected so it is not lost.
+					for key := range pending {
+						if !flush(key) {
+							return
+						}
+					}
+					return
+				}
+				isCRI, known := envelope[l.Source]
+				if !known && !l.Marker && strings.TrimSpace(l.Text) != "" {
+					_, isCRI = parse.SplitCRI(l.Text)
+					envelope[l.Source] = isCRI
+				}
+				cl, ok := parse.SplitCRI(l.Text)
+				if !isCRI || !ok {
+					if !emit(l) {
+						return
+					}
+					continue
+				}
+				key := l.Source + "\x00" + cl.Stream
+				p := pending[key]
+				if cl.Partial {
+					if p == nil {
+						p = &criPending{first: cl, line: l}
+						pending[key] = p
+					}
+					p.payload.WriteString(cl.Payload)
+					p.end = l.End
+					p.truncated += l.Truncated
+					p.last = time.Now()
+					continue
+				}
+				if p == nil {
+					if !emit(l) {
+						return
+					}
+					continue
+				}
+				delete(pending, key)
+				p.payload.WriteString(cl.Payload)
+				p.end = l.End
+				p.truncated += l.Truncated
+				if !emit(p.joined("F")) {
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+func (p *criPending) joined(tag string) Line {
+	l := p.line
+	l.Text = p.first.Time + " " + p.first.Stream + " " + tag + " " + p.payload.String()
+	l.End = p.end
+	l.Truncated = p.truncated
+	return l
+}
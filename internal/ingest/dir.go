@@ -0,0 +1,187 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"logsense/internal/util/logx"
+)
+
+// dirPollInterval is how often a watched directory is rescanned for new and
+// deleted files.
+const dirPollInterval = time.Second
+
+// dirMatches lists the regular files directly under dir whose name matches
+// include, sorted by name.
+func dirMatches(dir, include string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	out := []string{}
+	for _, e := range entries {
+		if ok, _ := filepath.Match(include, e.Name()); !ok {
+			continue
+		}
+		p := filepath.Join(dir, e.Name())
+		// Stat follows symlinks, as used by /var/log/containers
+		if st, err := os.Stat(p); err != nil || !st.Mode().IsRegular() {
+			continue
+		}
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// readDir reads every file in dir matching opt.Include, tagging lines with
+// the file name. Without follow the current files are read once. With follow
+// they are tailed from their end, files created later are tailed from their
+// start, and files that disappear stop being tailed.
+func readDir(ctx context.Context, dir string, opt Options, encs *encodingSet, out chan<- Line, errs chan<- error) {
+	include := opt.Include
+	if include == "" {
+		include = "*"
+	}
+	paths, err := dirMatches(dir, include)
+	if err != nil {
+		errs <- err
+		return
+	}
+	if !opt.Follow {
+		for _, p := range paths {
+			if ctx.Err() != nil {
+				return
+			}
+			readDirFileOnce(ctx, p, filepath.Base(p), opt, encs, out, errs)
+		}
+		return
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	// Tails are keyed by inode, so a file deleted and recreated between two
+	// polls is attached again and a renamed file is not read twice
+	type tail struct {
+		path   string
+		cancel context.CancelFunc
+	}
+	tails := map[string]tail{}
+	attach := func(key, path string, startOffset int64) {
+		if isCompressed(path) {
+			logx.Debugf("ingest: skipping compressed %s in watched directory", path)
+			tails[key] = tail{path: path, cancel: func() {}}
+			return
+		}
+		te := sniffFile(path, filepath.Base(path), opt.Encoding, encs)
+		if te.wide && startOffset != 0 {
+			// UTF-16 files cannot be tailed; only new ones are read, once
+			logx.Warnf("ingest: %s is %s; not following it", path, te.name)
+			tails[key] = tail{path: path, cancel: func() {}}
+			return
+		}
+		tctx, cancel := context.WithCancel(ctx)
+		tails[key] = tail{path: path, cancel: cancel}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if te.wide {
+				readWideFile(tctx, path, filepath.Base(path), te, Options{ScanBufSize: opt.ScanBufSize}, out, errs)
+				return
+			}
+			// Without inodes, replacements are only noticed by following the name
+			byName := strings.HasPrefix(key, "path:")
+			readFromTail(tctx, path, filepath.Base(path), startOffset, byName, opt.ScanBufSize, opt.State, out, errs)
+		}()
+	}
+	for _, p := range paths {
+		key, err := dirFileKey(p)
+		if err != nil {
+			continue
+		}
+		off, ok := opt.StartOffsets[filepath.Base(p)]
+		if !ok {
+			off = -1
+		}
+		attach(key, p, off)
+	}
+	ticker := time.NewTicker(dirPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		paths, err := dirMatches(dir, include)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				logx.Warnf("ingest: watched directory %s is gone", dir)
+			}
+			continue
+		}
+		seen := make(map[string]bool, len(paths))
+		for _, p := range paths {
+			key, err := dirFileKey(p)
+			if err != nil {
+				continue
+			}
+			seen[key] = true
+			if _, ok := tails[key]; !ok {
+				logx.Infof("ingest: attaching new file %s", p)
+				attach(key, p, 0)
+			}
+		}
+		for key, t := range tails {
+			if !seen[key] {
+				logx.Infof("ingest: detaching deleted file %s", t.path)
+				t.cancel()
+				delete(tails, key)
+			}
+		}
+	}
+}
+
+// dirFileKey identifies a watched file by its inode, or by its path where
+// inodes are not available. An inode cannot be reused while its tail holds
+// the file open.
+func dirFileKey(path string) (string, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if ino := fileInode(st); ino != 0 {
+		return "inode:" + strconv.FormatUint(ino, 10), nil
+	}
+	return "path:" + path, nil
+}
+
+func readDirFileOnce(ctx context.Context, path, src string, opt Options, encs *encodingSet, out chan<- Line, errs chan<- error) {
+	if te := sniffFile(path, src, opt.Encoding, encs); te.wide {
+		readWideFile(ctx, path, src, te, opt, out, errs)
+		return
+	}
+	rc, kind, err := openInput(path)
+	if err != nil {
+		errs <- err
+		return
+	}
+	defer rc.Close()
+	if opt.BlockSizeBytes > 0 {
+		readLastBytes(ctx, rc, src, opt.BlockSizeBytes, opt.ScanBufSize, out, errs)
+		return
+	}
+	// Offsets of plain files let a later follow continue where this stopped
+	base := int64(-1)
+	if kind == compressionNone {
+		base = 0
+	}
+	readFromReaderAt(ctx, rc, src, base, opt.ScanBufSize, out, errs)
+}
@@ -0,0 +1,253 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+
+	"logsense/internal/util/logx"
+)
+
+// encodingSniffBytes is how much of an input is inspected to guess its
+// character encoding.
+const encodingSniffBytes = 4096
+
+// textEncoding is the character encoding of an input. The zero value is
+// UTF-8, which needs no transcoding.
+type textEncoding struct {
+	name string
+	enc  encoding.Encoding
+	// wide encodings (UTF-16) encode '\n' in two bytes, so they must be
+	// decoded as a stream before lines are split; byte offsets are lost.
+	// Other encodings keep ASCII as is and are decoded line by line.
+	wide bool
+	// bom is set for UTF-8 input starting with a byte order mark
+	bom bool
+}
+
+var (
+	encUTF16LE = textEncoding{name: "utf-16le", enc: unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), wide: true}
+	encUTF16BE = textEncoding{name: "utf-16be", enc: unicode.UTF16(unicode.BigEndian, unicode.UseBOM), wide: true}
+	encLatin1  = textEncoding{name: "latin1", enc: charmap.ISO8859_1}
+	encWin1252 = textEncoding{name: "windows-1252", enc: charmap.Windows1252}
+	encSJIS    = textEncoding{name: "shift_jis", enc: japanese.ShiftJIS}
+	encUTF8BOM = textEncoding{name: "utf-8", bom: true}
+)
+
+// ValidateEncoding checks an --encoding value: "auto" (or ""), utf-8,
+// utf-16le, utf-16be, latin1, windows-1252 or shift_jis.
+func ValidateEncoding(name string) error {
+	_, _, err := lookupEncoding(name)
+	return err
+}
+
+// lookupEncoding resolves an encoding name; auto is true when the encoding
+// should be sniffed from the input instead.
+func lookupEncoding(name string) (te textEncoding, auto bool, err error) {
+	switch strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), "_", "-") {
+	case "", "auto":
+		return textEncoding{}, true, nil
+	case "utf-8", "utf8":
+		return textEncoding{}, false, nil
+	case "utf-16le", "utf16le", "utf-16", "utf16":
+		return encUTF16LE, false, nil
+	case "utf-16be", "utf16be":
+		return encUTF16BE, false, nil
+	case "latin1", "latin-1", "iso-8859-1", "iso8859-1":
+		return encLatin1, false, nil
+	case "windows-1252", "cp1252":
+		return encWin1252, false, nil
+	case "shift-jis", "sjis", "cp932":
+		return encSJIS, false, nil
+	}
+	return textEncoding{}, false, fmt.Errorf("unknown encoding %q (use auto, utf-8, utf-16le, utf-16be, latin1, windows-1252 or shift_jis)", name)
+}
+
+// sniffEncoding guesses the encoding of an input from its first bytes: a
+// byte order mark, then NUL bytes interleaved with ASCII (UTF-16 without
+// BOM), then invalid UTF-8, which is read as windows-1252 (a superset of
+// the printable latin1 range) when it is more than a stray byte.
+func sniffEncoding(head []byte) textEncoding {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return encUTF8BOM
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return encUTF16LE
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return encUTF16BE
+	}
+	if len(head) >= 4 {
+		var even, odd int
+		for i, b := range head {
+			if b == 0 {
+				if i%2 == 0 {
+					even++
+				} else {
+					odd++
+				}
+			}
+		}
+		half := len(head) / 2
+		switch {
+		case odd > half*3/4 && even < half/8:
+			return encUTF16LE
+		case even > half*3/4 && odd < half/8:
+			return encUTF16BE
+		}
+	}
+	var valid, invalid int
+	for i := 0; i < len(head); {
+		if head[i] < utf8.RuneSelf {
+			i++
+			continue
+		}
+		if !utf8.FullRune(head[i:]) {
+			// The sample ends in the middle of a character
+			break
+		}
+		r, n := utf8.DecodeRune(head[i:])
+		if r == utf8.RuneError && n == 1 {
+			invalid++
+		} else {
+			valid++
+		}
+		i += n
+	}
+	// A corrupt byte in UTF-8 text does not make it latin1: most non-ASCII
+	// characters must be invalid, and more than one
+	if invalid > 1 && invalid >= valid {
+		return encWin1252
+	}
+	return textEncoding{}
+}
+
+// resolveEncoding returns the encoding named by opt, or sniffs it from head.
+func resolveEncoding(name string, head []byte) textEncoding {
+	te, auto, err := lookupEncoding(name)
+	if err != nil || !auto {
+		return te
+	}
+	return sniffEncoding(head)
+}
+
+// fileEncoding resolves the encoding of the (decompressed) file at path.
+func fileEncoding(path, name string) textEncoding {
+	if te, auto, err := lookupEncoding(name); err != nil || !auto {
+		return te
+	}
+	rc, _, err := openInput(path)
+	if err != nil {
+		return textEncoding{}
+	}
+	defer rc.Close()
+	head := make([]byte, encodingSniffBytes)
+	n, _ := io.ReadFull(rc, head)
+	return sniffEncoding(head[:n])
+}
+
+// sniffFile resolves the encoding of the file at path, whose lines are
+// tagged src. A narrow encoding is registered in encs so its lines are
+// transcoded; a wide one is returned for the caller to decode the file as a
+// stream.
+func sniffFile(path, src, name string, encs *encodingSet) textEncoding {
+	te := fileEncoding(path, name)
+	if te.name != "" {
+		logx.Infof("ingest: %s encoding %s", path, te.name)
+	}
+	if te.needsLineDecode() {
+		encs.set(src, te)
+	}
+	return te
+}
+
+// IsWideEncoded reports whether the file at path is read through a UTF-16
+// decoder (named by encoding or sniffed). Its lines carry no byte offsets,
+// so reading cannot be followed or resumed.
+func IsWideEncoded(path, encoding string) bool {
+	return fileEncoding(path, encoding).wide
+}
+
+// reader decodes a wide encoding into UTF-8; other encodings are returned
+// as is and decoded per line.
+func (te textEncoding) reader(r io.Reader) io.Reader {
+	if !te.wide {
+		return r
+	}
+	return transform.NewReader(r, te.enc.NewDecoder())
+}
+
+// decodeLine transcodes one line of a narrow encoding into UTF-8.
+func (te textEncoding) decodeLine(s string) string {
+	if te.bom {
+		return strings.TrimPrefix(s, "\uFEFF")
+	}
+	if te.enc == nil || te.wide {
+		return s
+	}
+	if out, err := te.enc.NewDecoder().String(s); err == nil {
+		return out
+	}
+	return s
+}
+
+// needsLineDecode reports whether lines must pass through decodeLine.
+func (te textEncoding) needsLineDecode() bool {
+	return te.bom || (te.enc != nil && !te.wide)
+}
+
+// encodingSet maps sources to the narrow encoding their lines are decoded
+// from; fallback applies to sources without an entry.
+type encodingSet struct {
+	mu       sync.RWMutex
+	bySource map[string]textEncoding
+	fallback textEncoding
+}
+
+func newEncodingSet(fallback textEncoding) *encodingSet {
+	return &encodingSet{bySource: map[string]textEncoding{}, fallback: fallback}
+}
+
+func (s *encodingSet) set(src string, te textEncoding) {
+	s.mu.Lock()
+	s.bySource[src] = te
+	s.mu.Unlock()
+}
+
+func (s *encodingSet) get(src string) textEncoding {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if te, ok := s.bySource[src]; ok {
+		return te
+	}
+	return s.fallback
+}
+
+// transcode decodes lines of sources in narrow encodings into UTF-8. Line
+// offsets still refer to the raw bytes.
+func transcode(ctx context.Context, in <-chan Line, encs *encodingSet) <-chan Line {
+	out := make(chan Line, cap(in))
+	go func() {
+		defer close(out)
+		for l := range in {
+			if te := encs.get(l.Source); !l.Marker && te.needsLineDecode() {
+				l.Text = te.decodeLine(l.Text)
+			}
+			select {
+			case out <- l:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
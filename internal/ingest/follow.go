@@ -0,0 +1,154 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"time"
+
+	"logsense/internal/util/logx"
+)
+
+const (
+	// followPollInterval is how often a followed file is checked for new
+	// data, truncation and rotation once its end was reached.
+	followPollInterval = 250 * time.Millisecond
+)
+
+// readFromTail follows path like tail -F, starting at startOffset (or at the
+// end when negative). A file replaced by rename (different inode) is read to
+// its end and the new one is followed from the start; a file that shrinks
+// or is rewritten in place (copytruncate) is read again from offset 0. Both
+// are reported with a marker line and counted as rotations in st. With
+// byName false the open file is followed like tail -f instead, and a
+// replacement at path is left to the caller.
+func readFromTail(ctx context.Context, path, src string, startOffset int64, byName bool, maxBuf int, st *State, out chan<- Line, errs chan<- error) {
+	f, err := os.Open(path)
+	if err != nil {
+		errs <- err
+		return
+	}
+	defer func() { f.Close() }()
+	fi, err := f.Stat()
+	if err != nil {
+		errs <- err
+		return
+	}
+	pos := fi.Size()
+	if startOffset >= 0 && startOffset < pos {
+		pos = startOffset
+	}
+	if _, err := f.Seek(pos, io.SeekStart); err != nil {
+		errs <- err
+		return
+	}
+	lr := newLineReader(f, maxBuf)
+	send := func(l Line) bool {
+		select {
+		case out <- l:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	// drain reads complete lines up to the current end of f.
+	drain := func() bool {
+		for {
+			text, raw, dropped, err := lr.readLine()
+			if err != nil {
+				if err != io.EOF {
+					errs <- err
+				}
+				return true
+			}
+			if !send(Line{Text: text, Source: src, When: time.Now(), Offset: pos, End: pos + raw, Truncated: dropped}) {
+				return false
+			}
+			pos += raw
+		}
+	}
+	restart := func(nf *os.File, nfi os.FileInfo, notice string) bool {
+		logx.Infof("ingest: %s: %s", path, notice)
+		st.addRotation()
+		if nf != f {
+			f.Close()
+			f, fi = nf, nfi
+		} else if _, err := f.Seek(0, io.SeekStart); err != nil {
+			errs <- err
+			return false
+		}
+		lr.reset(f)
+		pos = 0
+		return send(Line{Text: notice, Source: src, When: time.Now(), Marker: true})
+	}
+	timer := time.NewTimer(followPollInterval)
+	defer timer.Stop()
+	for {
+		if !drain() {
+			return
+		}
+		timer.Reset(followPollInterval)
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		cur, err := os.Stat(path)
+		if !byName {
+			cur, err = f.Stat()
+		}
+		if err != nil {
+			// Moved away and not recreated yet: keep reading the old file
+			continue
+		}
+		if byName && !os.SameFile(cur, fi) {
+			nf, err := os.Open(path)
+			if err != nil {
+				continue
+			}
+			nfi, err := nf.Stat()
+			if err != nil {
+				nf.Close()
+				continue
+			}
+			// Finish the renamed file, including a last unterminated line
+			if !drain() {
+				nf.Close()
+				return
+			}
+			if lr.pending() > 0 {
+				text, raw, dropped := lr.take()
+				if !send(Line{Text: text, Source: src, When: time.Now(), Offset: pos, End: pos + raw, Truncated: dropped}) {
+					nf.Close()
+					return
+				}
+			}
+			if !restart(nf, nfi, "file rotated") {
+				return
+			}
+			continue
+		}
+		if cur.Size() < pos+lr.pending() || rewritten(f, pos+lr.pending(), lr.tail) {
+			// What was written after the last read and before the
+			// truncation cannot be measured any more
+			if !restart(f, cur, "file truncated, unread lines may be lost") {
+				return
+			}
+			continue
+		}
+	}
+}
+
+// rewritten reports whether the bytes before pos no longer end with mark,
+// i.e. the file was truncated and written again past pos between two polls.
+func rewritten(f *os.File, pos int64, mark []byte) bool {
+	if len(mark) == 0 || pos < int64(len(mark)) {
+		return false
+	}
+	buf := make([]byte, len(mark))
+	if _, err := f.ReadAt(buf, pos-int64(len(mark))); err != nil {
+		return true
+	}
+	return !bytes.Equal(buf, mark)
+}
@@ -0,0 +1,251 @@
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// indexSpacing is the minimum distance in bytes between two index
+// checkpoints; a line number lookup reads at most this much of the file.
+const indexSpacing = 256 << 10
+
+type indexPoint struct {
+	offset int64 // start of a line
+	line   int64 // 1-based number of that line
+}
+
+// Index is a sparse byte-offset index of one plain file, built from the
+// lines the reader emits. It maps offsets to line numbers so the UI can show
+// an absolute position for entries paged in from anywhere.
+type Index struct {
+	path  string
+	total int64 // file size when reading started
+
+	mu     sync.RWMutex
+	points []indexPoint
+	size   int64 // bytes indexed so far
+	lines  int64 // lines in [0, size)
+	done   bool
+	// times holds (offset, timestamp) samples, sorted by offset, taken at
+	// checkpoints once a TimeFunc is set and by time seeks.
+	times  []timePoint
+	timeOf TimeFunc
+}
+
+func newIndex(path string) *Index {
+	ix := &Index{path: path, points: []indexPoint{{offset: 0, line: 1}}}
+	if st, err := os.Stat(path); err == nil {
+		ix.total = st.Size()
+	}
+	return ix
+}
+
+// tap returns the channel the reader should send to: its lines are recorded
+// in the index and passed on to out. The returned function waits for them
+// once the reader is done. Only lines read contiguously from the start of
+// the file are counted, so a read that skips ahead (--block-size-mb, a time
+// range) leaves line numbers unknown rather than scanning the file twice.
+func (ix *Index) tap(ctx context.Context, out chan<- Line) (chan<- Line, func()) {
+	in := make(chan Line, cap(out))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for l := range in {
+			ix.observe(l)
+			select {
+			case out <- l:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return in, func() {
+		close(in)
+		<-done
+		ix.mu.Lock()
+		ix.done = ix.size > 0 && ix.size >= ix.total
+		ix.mu.Unlock()
+	}
+}
+
+// observe counts l and records a checkpoint every indexSpacing bytes.
+func (ix *Index) observe(l Line) {
+	if l.Marker || l.End == 0 {
+		return
+	}
+	ix.mu.Lock()
+	if l.Offset != ix.size {
+		ix.mu.Unlock()
+		return
+	}
+	checkpoint := l.Offset-ix.points[len(ix.points)-1].offset >= indexSpacing
+	if checkpoint {
+		ix.points = append(ix.points, indexPoint{offset: l.Offset, line: ix.lines + 1})
+	}
+	ix.lines++
+	ix.size = l.End
+	timeOf := ix.timeOf
+	ix.mu.Unlock()
+	if checkpoint && timeOf != nil {
+		if ts, ok := timeOf(l.Text); ok {
+			ix.addTime(l.Offset, ts)
+		}
+	}
+}
+
+// Path returns the indexed file.
+func (ix *Index) Path() string {
+	if ix == nil {
+		return ""
+	}
+	return ix.path
+}
+
+// Size returns the size of the file when reading started.
+func (ix *Index) Size() int64 {
+	if ix == nil {
+		return 0
+	}
+	return ix.total
+}
+
+// Lines returns the number of lines indexed so far and whether they cover
+// the whole file, in which case it is the file's line count.
+func (ix *Index) Lines() (int64, bool) {
+	if ix == nil {
+		return 0, false
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	return ix.lines, ix.done
+}
+
+// LineAt returns the 1-based line number of the line starting at offset. It
+// is unknown (false) until the lines up to that offset have been read.
+func (ix *Index) LineAt(offset int64) (int64, bool) {
+	if ix == nil || offset < 0 {
+		return 0, false
+	}
+	ix.mu.RLock()
+	if offset > ix.size {
+		ix.mu.RUnlock()
+		return 0, false
+	}
+	i := sort.Search(len(ix.points), func(i int) bool { return ix.points[i].offset > offset }) - 1
+	p := ix.points[i]
+	ix.mu.RUnlock()
+	if p.offset == offset {
+		return p.line, true
+	}
+	f, err := os.Open(ix.path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+	buf := make([]byte, offset-p.offset)
+	if _, err := f.ReadAt(buf, p.offset); err != nil && err != io.EOF {
+		return 0, false
+	}
+	return p.line + int64(bytes.Count(buf, []byte{'\n'})), true
+}
+
+// ReadBlockBefore reads the whole lines of path that end at or before
+// offset, going back about maxBytes. Lines run through the same CRI and
+// multiline stages as a live read and carry their offsets.
+func ReadBlockBefore(ctx context.Context, path string, offset, maxBytes int64, opt Options) ([]Line, error) {
+	if offset <= 0 {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	start := offset - maxBytes
+	for {
+		if start <= 0 {
+			start = 0
+			break
+		}
+		// Align to the first line start at or after start (the byte before it
+		// is a newline); widen the window when a single line is longer.
+		head := make([]byte, offset-start+1)
+		if _, err := f.ReadAt(head, start-1); err != nil && err != io.EOF {
+			return nil, err
+		}
+		if i := bytes.IndexByte(head, '\n'); i >= 0 && start+int64(i) < offset {
+			start += int64(i)
+			break
+		}
+		start -= maxBytes
+	}
+	return readBlock(ctx, f, start, offset, opt)
+}
+
+// ReadBlockAfter reads whole lines of path starting at offset (a line start)
+// until about maxBytes have been read or the end of the file is reached.
+func ReadBlockAfter(ctx context.Context, path string, offset, maxBytes int64, opt Options) ([]Line, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if offset >= st.Size() {
+		return nil, nil
+	}
+	end := offset + maxBytes
+	if end >= st.Size() {
+		end = st.Size()
+	} else {
+		// Extend to the end of the line that crosses the limit
+		buf := make([]byte, 64<<10)
+		for end < st.Size() {
+			n, err := f.ReadAt(buf, end)
+			if i := bytes.IndexByte(buf[:n], '\n'); i >= 0 {
+				end += int64(i) + 1
+				break
+			}
+			end += int64(n)
+			if err != nil {
+				break
+			}
+		}
+	}
+	return readBlock(ctx, f, offset, end, opt)
+}
+
+// readBlock reads the lines in [start, end) of f through the record stages.
+func readBlock(ctx context.Context, f *os.File, start, end int64, opt Options) ([]Line, error) {
+	src := f.Name()
+	in := make(chan Line, 1024)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(in)
+		readFromReaderAt(ctx, io.NewSectionReader(f, start, end-start), src, start, opt.ScanBufSize, in, errs)
+	}()
+	var lines <-chan Line = in
+	if te := fileEncoding(src, opt.Encoding); te.needsLineDecode() {
+		lines = transcode(ctx, lines, newEncodingSet(te))
+	}
+	lines = joinCRIPartials(ctx, lines, opt.Multiline.Timeout)
+	if opt.Multiline.enabled() {
+		lines = multiline(ctx, lines, opt.Multiline)
+	}
+	out := []Line{}
+	for l := range lines {
+		out = append(out, l)
+	}
+	select {
+	case err := <-errs:
+		return out, err
+	default:
+	}
+	return out, ctx.Err()
+}
@@ -0,0 +1,155 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxHTTPBody bounds a single POSTed body (after decompression).
+const maxHTTPBody = 64 << 20
+
+// serveHTTP runs handler on addr until ctx is cancelled.
+func serveHTTP(ctx context.Context, addr string, handler http.Handler, errs chan<- error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		errs <- err
+		return
+	}
+	srv := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		errs <- err
+	}
+}
+
+// requestSource picks the source tag for a request: the X-Source header when
+// present, otherwise the client address.
+func requestSource(r *http.Request) string {
+	if s := strings.TrimSpace(r.Header.Get("X-Source")); s != "" {
+		return s
+	}
+	return r.RemoteAddr
+}
+
+// requestBody returns the request body, transparently un-gzipping it. The
+// caller closes it when done.
+func requestBody(r *http.Request) (io.ReadCloser, error) {
+	var body io.ReadCloser = r.Body
+	if strings.EqualFold(r.Header.Get("Content-Encoding"), "gzip") {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, err
+		}
+		body = zr
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{io.LimitReader(body, maxHTTPBody+1), body}, nil
+}
+
+// httpIngestHandler accepts POSTed NDJSON, newline-separated text or a JSON
+// array of records. Every record becomes one line.
+func httpIngestHandler(ctx context.Context, out chan<- Line) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut {
+			w.Header().Set("Allow", "POST, PUT")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := requestBody(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(data) > maxHTTPBody {
+			http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		records, err := splitHTTPRecords(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		src := requestSource(r)
+		now := time.Now()
+		for _, rec := range records {
+			select {
+			case out <- Line{Text: rec, Source: src, When: now}:
+			case <-ctx.Done():
+				http.Error(w, "shutting down", http.StatusServiceUnavailable)
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// splitHTTPRecords turns a request body into records. A JSON array yields one
+// record per element (objects re-encoded compactly, strings as-is); anything
+// else, including text that only starts with "[" (e.g. "[INFO] started"), is
+// split on newlines, which covers both NDJSON and plain text.
+func splitHTTPRecords(data []byte) ([]string, error) {
+	if out, ok := splitJSONArray(data); ok {
+		return out, nil
+	}
+	out := []string{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), maxHTTPBody)
+	for sc.Scan() {
+		t := strings.TrimRight(sc.Text(), "\r")
+		if strings.TrimSpace(t) == "" {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out, sc.Err()
+}
+
+// splitJSONArray returns the elements of data when it is a JSON array.
+func splitJSONArray(data []byte) ([]string, bool) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return nil, false
+	}
+	var items []json.RawMessage
+	if err := json.Unmarshal(trimmed, &items); err != nil {
+		return nil, false
+	}
+	out := make([]string, 0, len(items))
+	for _, it := range items {
+		var s string
+		if err := json.Unmarshal(it, &s); err == nil {
+			out = append(out, s)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, it); err != nil {
+			return nil, false
+		}
+		out = append(out, buf.String())
+	}
+	return out, true
+}
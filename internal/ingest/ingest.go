@@ -2,13 +2,15 @@ package ingest
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"errors"
 	"io"
 	"os"
+	"sync"
 	"time"
 
-	"github.com/nxadm/tail"
+	"logsense/internal/util/logx"
 )
 
 type SourceKind string
@@ -17,28 +19,119 @@ const (
 	SourceStdin SourceKind = "stdin"
 	SourceFile  SourceKind = "file"
 	SourceDemo  SourceKind = "demo"
+	// SourceRotated reads a logrotate set: Paths[0] is the live file and its
+	// rotated siblings are streamed oldest-first before it.
+	SourceRotated SourceKind = "rotated"
+	// SourceCommand runs Command through the shell and reads its stdout and
+	// stderr, restarting it on exit while State asks for supervision.
+	SourceCommand SourceKind = "cmd"
+	// SourceSyslog listens on Listen (udp://, tcp://, unix://, unixgram://)
+	// for syslog messages and tags each one with the peer address.
+	SourceSyslog SourceKind = "syslog"
+	// SourceHTTP serves HTTP on Listen (host:port) and accepts POSTed NDJSON,
+	// plain text or JSON arrays; X-Source sets the source tag.
+	SourceHTTP SourceKind = "http"
+	// SourceOTLP serves the OTLP/HTTP logs endpoint (/v1/logs, JSON or
+	// protobuf) on Listen and flattens each LogRecord into a JSON line.
+	SourceOTLP SourceKind = "otlp"
+	// SourceDir watches the directory Paths[0] and reads every file matching
+	// Include, attaching files created later; lines carry the file name.
+	SourceDir SourceKind = "dir"
+	// SourceCapture reopens a file written by a Recorder (--record).
+	SourceCapture SourceKind = "capture"
 )
 
 type Options struct {
-	Source         SourceKind
-	Path           string
+	Source SourceKind
+	// Paths lists the files to read for SourceFile; lines from all of them are
+	// merged into one stream and tagged with their originating path.
+	Paths          []string
 	Follow         bool
-	ScanBufSize    int   // per-line max (bytes)
+	ScanBufSize    int   // per-line max (bytes); longer lines are cut to it
 	BlockSizeBytes int64 // only for non-follow file read; 0 = all
-	// StartOffset: when following a file, if >= 0, start reading from this
-	// absolute byte offset (from start). If < 0, start at file end.
+	// StartOffset: when following a single file, if >= 0, start reading from
+	// this absolute byte offset (from start). If < 0, start at file end.
+	// Ignored when following several files, which all start at their end.
 	StartOffset int64
+	// StartOffsets continues following files where a previous read stopped.
+	// It is keyed by Line.Source and overrides StartOffset for those files;
+	// a rotated set resumes its live file without rereading the siblings.
+	StartOffsets map[string]int64
+	// SinceLast resumes each file from the checkpoint saved by the previous
+	// session (see SaveCheckpoint) instead of StartOffset/BlockSizeBytes.
+	SinceLast bool
+	// Multiline joins continuation lines (stack traces, wrapped messages)
+	// into the preceding record before they reach the parser.
+	Multiline MultilineOptions
+	// Since and Until bound a single plain file by record time; the bounds
+	// are located with SeekTime using TimeOf instead of scanning the file.
+	// Zero values leave that side open.
+	Since  time.Time
+	Until  time.Time
+	TimeOf TimeFunc
+	// Replay paces records by their TimeOf timestamps (arrival times for
+	// SourceCapture), sped up by this factor; 0 reads as fast as possible.
+	Replay float64
+	// Include is the file name glob for SourceDir ("" matches every file).
+	Include string
+	// Command is the shell command line for SourceCommand.
+	Command string
+	// Listen is the listen URL for network sources.
+	Listen string
+	// Encoding is the character encoding of file, stdin and command input
+	// ("" or "auto" sniffs files and stdin); see ValidateEncoding for the
+	// names. Network sources and captures are always UTF-8.
+	Encoding string
+	// Record, when set, receives every input line (transcoded to UTF-8)
+	// before the record stages.
+	Record *Recorder
+	// State receives runtime status (process state, counters) for the UI.
+	State *State
 }
 
 type Line struct {
 	Text   string
 	Source string
 	When   time.Time
+	// Offset and End delimit the record in its file: Offset is where its
+	// first byte is and End is just past its trailing newline. End is zero
+	// when the line does not come from a seekable file.
+	Offset int64
+	End    int64
+	// Marker flags a notice generated by the reader (e.g. "file truncated")
+	// rather than a line of input; Text is the notice.
+	Marker bool
+	// Truncated is the number of bytes cut from the end of an oversized
+	// line (see Options.ScanBufSize).
+	Truncated int64
 }
 
 func Read(ctx context.Context, opt Options) (<-chan Line, <-chan error) {
 	out := make(chan Line, 1024)
 	errs := make(chan error, 1)
+	encs := newEncodingSet(textEncoding{})
+	if te, _, _ := lookupEncoding(opt.Encoding); opt.Source == SourceCommand && te.needsLineDecode() {
+		// Files and stdin register their encoding per source; command
+		// output cannot be sniffed up front and takes a forced one
+		encs.fallback = te
+	}
+	var raw <-chan Line = transcode(ctx, out, encs)
+	if opt.Record != nil {
+		raw = record(ctx, raw, opt.Record)
+	}
+	lines := joinCRIPartials(ctx, raw, opt.Multiline.Timeout)
+	if opt.Multiline.enabled() {
+		lines = multiline(ctx, lines, opt.Multiline)
+	}
+	if opt.Replay > 0 {
+		if opt.Source == SourceCapture {
+			// Captures replay their original arrival times
+			lines = replay(ctx, lines, opt.Replay, func(l Line) (time.Time, bool) { return l.When, true }, opt.State)
+		} else if opt.TimeOf != nil {
+			timeOf := opt.TimeOf
+			lines = replay(ctx, lines, opt.Replay, func(l Line) (time.Time, bool) { return timeOf(l.Text) }, opt.State)
+		}
+	}
 
 	go func() {
 		defer close(out)
@@ -46,21 +139,94 @@ func Read(ctx context.Context, opt Options) (<-chan Line, <-chan error) {
 
 		switch opt.Source {
 		case SourceStdin:
-			readFromReader(ctx, os.Stdin, "stdin", opt.ScanBufSize, out, errs)
+			readFromReader(ctx, stdinReader(opt.Encoding, encs), "stdin", opt.ScanBufSize, out, errs)
 		case SourceFile:
-			if opt.Follow {
-				readFromTail(ctx, opt.Path, opt.StartOffset, out, errs)
-			} else if opt.BlockSizeBytes > 0 {
-				readFromFileBlock(ctx, opt.Path, opt.BlockSizeBytes, opt.ScanBufSize, out, errs)
-			} else {
-				f, err := os.Open(opt.Path)
-				if err != nil {
-					errs <- err
-					return
+			if len(opt.Paths) == 0 {
+				errs <- errors.New("no input files")
+				return
+			}
+			startOffset := opt.StartOffset
+			if len(opt.Paths) > 1 {
+				startOffset = -1
+			}
+			// UTF-16 files are decoded as a stream, without offsets
+			wide := map[string]textEncoding{}
+			for _, p := range opt.Paths {
+				if te := sniffFile(p, p, opt.Encoding, encs); te.wide {
+					wide[p] = te
 				}
-				defer f.Close()
-				readFromReader(ctx, f, opt.Path, opt.ScanBufSize, out, errs)
 			}
+			opt.State.setIndex(nil)
+			var ix *Index
+			if _, isWide := wide[opt.Paths[0]]; len(opt.Paths) == 1 && isWide {
+				readWideFile(ctx, opt.Paths[0], opt.Paths[0], wide[opt.Paths[0]], opt, out, errs)
+				return
+			}
+			var dst chan<- Line = out
+			if len(opt.Paths) == 1 && !opt.Follow && !isCompressed(opt.Paths[0]) {
+				// Lets the UI page entries evicted from the ring back in
+				ix = newIndex(opt.Paths[0])
+				ix.SetTimeFunc(opt.TimeOf)
+				opt.State.setIndex(ix)
+				var finish func()
+				dst, finish = ix.tap(ctx, out)
+				defer finish()
+			}
+			if len(opt.Paths) == 1 && opt.hasTimeRange() && !isCompressed(opt.Paths[0]) {
+				readTimeRange(ctx, opt.Paths[0], opt, ix, dst, errs)
+				return
+			}
+			var wg sync.WaitGroup
+			for _, p := range opt.Paths {
+				wg.Add(1)
+				go func(path string) {
+					defer wg.Done()
+					if te, ok := wide[path]; ok {
+						readWideFile(ctx, path, path, te, opt, out, errs)
+						return
+					}
+					if opt.SinceLast {
+						readSinceCheckpoint(ctx, path, opt, dst, errs)
+						return
+					}
+					off := startOffset
+					if o, ok := opt.StartOffsets[path]; ok {
+						off = o
+					}
+					readFile(ctx, path, opt, off, dst, errs)
+				}(p)
+			}
+			wg.Wait()
+		case SourceRotated:
+			if len(opt.Paths) == 0 {
+				errs <- errors.New("no base path for rotated set")
+				return
+			}
+			readRotated(ctx, opt.Paths[0], opt, encs, out, errs)
+		case SourceDir:
+			if len(opt.Paths) == 0 {
+				errs <- errors.New("no directory to watch")
+				return
+			}
+			readDir(ctx, opt.Paths[0], opt, encs, out, errs)
+		case SourceCommand:
+			if opt.Command == "" {
+				errs <- errors.New("empty command")
+				return
+			}
+			readFromCommand(ctx, opt.Command, opt, out, errs)
+		case SourceSyslog:
+			readFromSyslog(ctx, opt.Listen, opt.ScanBufSize, out, errs)
+		case SourceHTTP:
+			serveHTTP(ctx, opt.Listen, httpIngestHandler(ctx, out), errs)
+		case SourceOTLP:
+			serveHTTP(ctx, opt.Listen, otlpLogsHandler(ctx, out), errs)
+		case SourceCapture:
+			if len(opt.Paths) == 0 {
+				errs <- errors.New("no capture file")
+				return
+			}
+			readCapture(ctx, opt.Paths[0], opt.ScanBufSize, out, errs)
 		case SourceDemo:
 			demo(ctx, out)
 		default:
@@ -68,61 +234,161 @@ func Read(ctx context.Context, opt Options) (<-chan Line, <-chan error) {
 		}
 	}()
 
-	return out, errs
+	return lines, errs
 }
 
-func readFromReader(ctx context.Context, r io.Reader, src string, maxBuf int, out chan<- Line, errs chan<- error) {
-	scanner := bufio.NewScanner(r)
-	buf := make([]byte, 0, 1024*64)
-	scanner.Buffer(buf, maxBuf)
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
+func (o Options) hasTimeRange() bool {
+	return o.TimeOf != nil && (!o.Since.IsZero() || !o.Until.IsZero())
+}
+
+// readTimeRange reads the part of a plain file between opt.Since and
+// opt.Until. With follow, it tails from the first record at or after Since.
+func readTimeRange(ctx context.Context, path string, opt Options, ix *Index, out chan<- Line, errs chan<- error) {
+	from, to := int64(0), int64(-1)
+	if !opt.Since.IsZero() {
+		off, err := SeekTime(ctx, path, func(t time.Time) bool { return !t.Before(opt.Since) }, opt.TimeOf, ix)
+		if err != nil {
+			errs <- err
+			return
+		}
+		from = off
+	}
+	if opt.Follow {
+		readFromTail(ctx, path, path, from, true, opt.ScanBufSize, opt.State, out, errs)
+		return
+	}
+	if !opt.Until.IsZero() {
+		off, err := SeekTime(ctx, path, func(t time.Time) bool { return t.After(opt.Until) }, opt.TimeOf, ix)
+		if err != nil {
+			errs <- err
 			return
-		default:
 		}
-		out <- Line{Text: scanner.Text(), Source: src, When: time.Now()}
+		to = off
 	}
-	if err := scanner.Err(); err != nil {
+	f, err := os.Open(path)
+	if err != nil {
 		errs <- err
+		return
 	}
+	defer f.Close()
+	if to < 0 {
+		st, err := f.Stat()
+		if err != nil {
+			errs <- err
+			return
+		}
+		to = st.Size()
+	}
+	logx.Infof("ingest: time range of %s is bytes %d-%d", path, from, to)
+	if to <= from {
+		return
+	}
+	readFromReaderAt(ctx, io.NewSectionReader(f, from, to-from), path, from, opt.ScanBufSize, out, errs)
+}
+
+// readFile reads a single file according to the follow/block options.
+// Compressed files are decompressed on the fly and cannot be followed.
+func readFile(ctx context.Context, path string, opt Options, startOffset int64, out chan<- Line, errs chan<- error) {
+	compressed := isCompressed(path)
+	if opt.Follow && !compressed {
+		readFromTail(ctx, path, path, startOffset, true, opt.ScanBufSize, opt.State, out, errs)
+		return
+	}
+	if opt.Follow {
+		logx.Warnf("ingest: %s is compressed; reading it without follow", path)
+	}
+	if opt.BlockSizeBytes > 0 && !compressed {
+		readFromFileBlock(ctx, path, opt.BlockSizeBytes, opt.ScanBufSize, out, errs)
+		return
+	}
+	rc, kind, err := openInput(path)
+	if err != nil {
+		errs <- err
+		return
+	}
+	defer rc.Close()
+	if opt.BlockSizeBytes > 0 {
+		readLastBytes(ctx, rc, path, opt.BlockSizeBytes, opt.ScanBufSize, out, errs)
+		return
+	}
+	base := int64(-1)
+	if kind == compressionNone {
+		base = 0
+	}
+	readFromReaderAt(ctx, rc, path, base, opt.ScanBufSize, out, errs)
 }
 
-func readFromTail(ctx context.Context, path string, startOffset int64, out chan<- Line, errs chan<- error) {
-	var loc *tail.SeekInfo
-	if startOffset >= 0 {
-		loc = &tail.SeekInfo{Offset: startOffset, Whence: io.SeekStart}
-	} else {
-		loc = &tail.SeekInfo{Offset: 0, Whence: io.SeekEnd}
-	}
-	t, err := tail.TailFile(path, tail.Config{
-		Follow:    true,
-		ReOpen:    true,
-		MustExist: true,
-		Logger:    tail.DiscardingLogger,
-		Poll:      true,
-		Location:  loc,
-	})
+// readWideFile reads a UTF-16 file, transcoding it to UTF-8 as a stream of
+// lines tagged src. Lines carry no offsets, and the file is read once even
+// with follow.
+func readWideFile(ctx context.Context, path, src string, te textEncoding, opt Options, out chan<- Line, errs chan<- error) {
+	if opt.Follow {
+		logx.Warnf("ingest: %s is %s; reading it without follow", path, te.name)
+	}
+	rc, _, err := openInput(path)
 	if err != nil {
 		errs <- err
 		return
 	}
-	defer t.Cleanup()
+	defer rc.Close()
+	r := te.reader(rc)
+	if opt.BlockSizeBytes > 0 {
+		readLastBytes(ctx, r, src, opt.BlockSizeBytes, opt.ScanBufSize, out, errs)
+		return
+	}
+	readFromReader(ctx, r, src, opt.ScanBufSize, out, errs)
+}
+
+// stdinReader returns stdin decoded from the named encoding. When it is
+// sniffed, only the first chunk available is inspected so a slow pipe is
+// not held back.
+func stdinReader(name string, encs *encodingSet) io.Reader {
+	var r io.Reader = os.Stdin
+	te, auto, _ := lookupEncoding(name)
+	if auto {
+		head := make([]byte, encodingSniffBytes)
+		n, _ := os.Stdin.Read(head)
+		te = sniffEncoding(head[:n])
+		r = io.MultiReader(bytes.NewReader(head[:n]), os.Stdin)
+	}
+	if te.needsLineDecode() {
+		encs.set("stdin", te)
+	}
+	if te.name != "" {
+		logx.Infof("ingest: stdin encoding %s", te.name)
+	}
+	return te.reader(r)
+}
+
+func readFromReader(ctx context.Context, r io.Reader, src string, maxBuf int, out chan<- Line, errs chan<- error) {
+	readFromReaderAt(ctx, r, src, -1, maxBuf, out, errs)
+}
+
+// readFromReaderAt is readFromReader for a plain file positioned at byte
+// offset base: every line carries its Offset and End in the file. A negative
+// base disables offset tracking.
+func readFromReaderAt(ctx context.Context, r io.Reader, src string, base int64, maxBuf int, out chan<- Line, errs chan<- error) {
+	lr := newLineReader(r, maxBuf)
+	pos := base
 	for {
+		text, raw, dropped, err := lr.next()
+		if err != nil {
+			if err != io.EOF {
+				errs <- err
+			}
+			return
+		}
 		select {
 		case <-ctx.Done():
-			t.Stop()
 			return
-		case l, ok := <-t.Lines:
-			if !ok {
-				return
-			}
-			if l.Err != nil {
-				errs <- l.Err
-				continue
-			}
-			out <- Line{Text: l.Text, Source: path, When: time.Now()}
+		default:
 		}
+		l := Line{Text: text, Source: src, When: time.Now(), Truncated: dropped}
+		if base >= 0 {
+			l.Offset, l.End = pos, pos+raw
+			pos = l.End
+		}
+		out <- l
 	}
 }
 
@@ -149,20 +415,18 @@ func readFromFileBlock(ctx context.Context, path string, blockBytes int64, maxBu
 		}
 		// Drop partial first line
 		br := bufio.NewReader(f)
-		if _, err := br.ReadString('\n'); err != nil && err != io.EOF {
+		partial, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
 			errs <- err
 			return
 		}
 		// Continue with scanner on remaining reader
-		readFromReader(ctx, br, path, maxBuf, out, errs)
+		readFromReaderAt(ctx, br, path, start+int64(len(partial)), maxBuf, out, errs)
 		return
 	}
-	readFromReader(ctx, f, path, maxBuf, out, errs)
+	readFromReaderAt(ctx, f, path, 0, maxBuf, out, errs)
 }
 
-// Read last N blocks (blockBytes each) from file and return lines (utility for paging older data).
-// Removed file block helpers (ReadFileBlocks/ReadFileBlock) and scanAll since paging was dropped.
-
 func demo(ctx context.Context, out chan<- Line) {
 	samples := []string{
 		`{"ts":"2025-01-01T12:00:00Z","level":"info","service":"api","msg":"server started","port":8080}`,
@@ -0,0 +1,773 @@
+package ingest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func collect(t *testing.T, opt Options) []Line {
+	t.Helper()
+	lines, errs := Read(context.Background(), opt)
+	out := []Line{}
+	for l := range lines {
+		out = append(out, l)
+	}
+	for err := range errs {
+		t.Fatalf("ingest error: %v", err)
+	}
+	return out
+}
+
+func TestReadSeveralFilesTagsSources(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.log"), filepath.Join(dir, "b.log")
+	os.WriteFile(a, []byte("a1\na2\n"), 0o644)
+	os.WriteFile(b, []byte("b1\n"), 0o644)
+
+	got := collect(t, Options{Source: SourceFile, Paths: []string{a, b}, ScanBufSize: 1024})
+	bySource := map[string][]string{}
+	for _, l := range got {
+		bySource[l.Source] = append(bySource[l.Source], l.Text)
+	}
+	if len(got) != 3 || len(bySource[a]) != 2 || bySource[a][1] != "a2" || len(bySource[b]) != 1 || bySource[b][0] != "b1" {
+		t.Fatalf("unexpected lines: %+v", got)
+	}
+}
+
+func TestReadGzipFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log.1.gz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := gzip.NewWriter(f)
+	zw.Write([]byte("one\ntwo\nthree\n"))
+	zw.Close()
+	f.Close()
+
+	got := collect(t, Options{Source: SourceFile, Paths: []string{path}, ScanBufSize: 1024})
+	if len(got) != 3 || got[0].Text != "one" || got[2].Text != "three" {
+		t.Fatalf("unexpected lines: %+v", got)
+	}
+	got = collect(t, Options{Source: SourceFile, Paths: []string{path}, ScanBufSize: 1024, BlockSizeBytes: 10})
+	if len(got) != 2 || got[0].Text != "two" {
+		t.Fatalf("unexpected block lines: %+v", got)
+	}
+}
+
+func TestRotatedSiblingsOrder(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "app.log")
+	for _, n := range []string{"app.log", "app.log.1", "app.log.2.gz", "app.log.10.gz", "app.log.bak", "other.log.1"} {
+		if err := os.WriteFile(filepath.Join(dir, n), []byte("x\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := rotatedSiblings(base)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"app.log.10.gz", "app.log.2.gz", "app.log.1"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v", got)
+	}
+	for i := range want {
+		if filepath.Base(got[i]) != want[i] {
+			t.Fatalf("position %d: got %s want %s", i, filepath.Base(got[i]), want[i])
+		}
+	}
+}
+
+func TestMultilineJoinsStackTrace(t *testing.T) {
+	in := make(chan Line, 16)
+	for _, s := range []string{
+		"2025-01-01 12:00:00 ERROR boom",
+		"java.lang.IllegalStateException: bad",
+		"\tat com.example.Foo.bar(Foo.java:10)",
+		"Caused by: java.io.IOException: io",
+		"\t... 3 more",
+		"2025-01-01 12:00:01 INFO next",
+	} {
+		in <- Line{Text: s, Source: "app"}
+	}
+	close(in)
+	rules, _ := ParseMultilineRules("auto")
+	out := []Line{}
+	for l := range multiline(context.Background(), in, MultilineOptions{Rules: rules}) {
+		out = append(out, l)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected 2 records, got %d: %+v", len(out), out)
+	}
+	if n := len(strings.Split(out[0].Text, "\n")); n != 5 {
+		t.Fatalf("expected 5 joined lines, got %d", n)
+	}
+}
+
+func TestCommandSourceTagsStreams(t *testing.T) {
+	st := NewState()
+	st.SetSupervise(false)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, _ := Read(ctx, Options{Source: SourceCommand, Command: "echo out; echo err >&2", ScanBufSize: 1024, State: st})
+	next := func() map[string]string {
+		bySrc := map[string]string{}
+		for len(bySrc) < 2 {
+			select {
+			case l := <-lines:
+				bySrc[l.Source] = l.Text
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out, got %v", bySrc)
+			}
+		}
+		return bySrc
+	}
+	if bySrc := next(); bySrc["stdout"] != "out" || bySrc["stderr"] != "err" {
+		t.Fatalf("unexpected lines: %+v", bySrc)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for proc, _ := st.Process(); !strings.HasPrefix(proc, "exited"); proc, _ = st.Process() {
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected process state %q", proc)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	// Turning supervision on starts the exited command again
+	st.SetSupervise(true)
+	if bySrc := next(); bySrc["stdout"] != "out" {
+		t.Fatalf("command not restarted: %+v", bySrc)
+	}
+	if _, restarts := st.Process(); restarts < 1 {
+		t.Fatalf("restart not counted")
+	}
+}
+
+func TestSyslogFramesOctetCountedAndNewline(t *testing.T) {
+	msg := "<34>1 2025-01-01T12:00:00Z h a"
+	in := fmt.Sprintf("%d %s\n<13>Jan  1 12:00:00 host app: plain\n<13>%s\n", len(msg), msg, strings.Repeat("x", 100))
+	out := make(chan Line, 4)
+	readSyslogFrames(context.Background(), newLineReader(strings.NewReader(in), 40), "peer", out)
+	close(out)
+	got := []Line{}
+	for l := range out {
+		got = append(got, l)
+	}
+	if len(got) != 3 || got[0].Text != msg || got[1].Text != "<13>Jan  1 12:00:00 host app: plain" {
+		t.Fatalf("unexpected frames: %+v", got)
+	}
+	// A newline-framed message past the line limit is cut, not buffered whole
+	if len(got[2].Text) != 40 || got[2].Truncated != 64 {
+		t.Fatalf("unexpected long frame: %+v", got[2])
+	}
+}
+
+func TestHTTPIngestRecords(t *testing.T) {
+	out := make(chan Line, 8)
+	h := httpIngestHandler(context.Background(), out)
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"msg":"a", "n": 1},"plain"]`))
+	req.Header.Set("X-Source", "suite")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status %d", rec.Code)
+	}
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{\"msg\":\"b\"}\n\nline two\n"))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	// Text that merely starts with "[" is not a JSON array
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("[INFO] started\n[WARN] slow\n"))
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("text body status %d", rec.Code)
+	}
+	close(out)
+	got := []Line{}
+	for l := range out {
+		got = append(got, l)
+	}
+	if len(got) != 6 || got[0].Text != `{"msg":"a","n":1}` || got[0].Source != "suite" || got[1].Text != "plain" || got[3].Text != "line two" || got[4].Text != "[INFO] started" || got[5].Text != "[WARN] slow" {
+		t.Fatalf("unexpected lines: %+v", got)
+	}
+}
+
+func TestOTLPLogsJSONAndProtobuf(t *testing.T) {
+	out := make(chan Line, 8)
+	h := otlpLogsHandler(context.Background(), out)
+	body := `{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"checkout"}}]},
+		"scopeLogs":[{"logRecords":[{"timeUnixNano":"1700000000000000000","severityNumber":17,"body":{"stringValue":"boom"},
+		"attributes":[{"key":"http","value":{"kvlistValue":{"values":[{"key":"status","value":{"intValue":"500"}}]}}}]}]}]}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+
+	// ExportLogsServiceRequest{resource_logs{scope_logs{log_records{severity_text, body}}}}
+	str := func(num protowire.Number, s string) []byte {
+		return protowire.AppendBytes(protowire.AppendTag(nil, num, protowire.BytesType), []byte(s))
+	}
+	msg := func(num protowire.Number, b []byte) []byte {
+		return protowire.AppendBytes(protowire.AppendTag(nil, num, protowire.BytesType), b)
+	}
+	lr := append(str(3, "warn"), msg(5, str(1, "disk low"))...)
+	payload := msg(1, msg(2, msg(2, lr)))
+	req = httptest.NewRequest(http.MethodPost, "/v1/logs", bytes.NewReader(payload))
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	close(out)
+	got := []Line{}
+	for l := range out {
+		got = append(got, l)
+	}
+	if len(got) != 2 {
+		t.Fatalf("want 2 lines, got %+v", got)
+	}
+	want := `{"http.status":500,"level":"ERROR","msg":"boom","service.name":"checkout","ts":"2023-11-14T22:13:20Z"}`
+	if got[0].Text != want || got[0].Source != "checkout" {
+		t.Fatalf("json record: %+v", got[0])
+	}
+	if got[1].Text != `{"level":"warn","msg":"disk low"}` {
+		t.Fatalf("protobuf record: %+v", got[1])
+	}
+}
+
+func TestCRIPartialsJoined(t *testing.T) {
+	got := collect(t, Options{Source: SourceFile, Paths: []string{"../../testdata/k8s_cri.log"}, StartOffset: -1})
+	if len(got) != 4 {
+		t.Fatalf("want 4 records, got %d: %v", len(got), got)
+	}
+	want := `2025-01-01T12:00:02.000000000Z stdout F {"ts":"2025-01-01T12:00:02Z","level":"error","msg":"split by the runtime"}`
+	if got[2].Text != want {
+		t.Fatalf("joined record:\n got %s\nwant %s", got[2].Text, want)
+	}
+}
+
+func TestCRIPartialFlushedAfterTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	in := make(chan Line, 4)
+	out := joinCRIPartials(ctx, in, 100*time.Millisecond)
+	in <- Line{Text: "plain first line", Source: "other"}
+	in <- Line{Text: "2025-01-01T12:00:00Z stdout P not joined", Source: "other"}
+	in <- Line{Text: "2025-01-01T12:00:01Z stdout P dangling", Source: "pod"}
+	for _, want := range []string{"plain first line", "2025-01-01T12:00:00Z stdout P not joined"} {
+		if l := <-out; l.Text != want {
+			t.Fatalf("non-CRI source changed: got %q want %q", l.Text, want)
+		}
+	}
+	// The input stays open, as when following: the partial must still appear
+	select {
+	case l := <-out:
+		if l.Text != "2025-01-01T12:00:01Z stdout P dangling" {
+			t.Fatalf("unexpected flush %q", l.Text)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("partial record not flushed")
+	}
+}
+
+func TestDirWatchAttachesNewFiles(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.log")
+	if err := os.WriteFile(a, []byte("old\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "skip.txt"), []byte("ignored\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, _ := Read(ctx, Options{Source: SourceDir, Paths: []string{dir}, Include: "*.log", Follow: true})
+	// waitFor reads lines until want has arrived from src, calling poke
+	// between reads until then
+	seen := map[string]bool{}
+	waitFor := func(src, want string, poke func()) {
+		t.Helper()
+		deadline := time.After(5 * time.Second)
+		for !seen[src+": "+want] {
+			poke()
+			select {
+			case l := <-lines:
+				seen[l.Source+": "+l.Text] = true
+			case <-time.After(100 * time.Millisecond):
+			case <-deadline:
+				t.Fatalf("timed out waiting for %q from %s, got %v", want, src, seen)
+			}
+		}
+	}
+	// a.log is tailed from its end; append until the tail has picked it up
+	waitFor("a.log", "from a", func() {
+		f, err := os.OpenFile(a, os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fmt.Fprintln(f, "from a")
+		f.Close()
+	})
+	// Once a.log is tailed the initial scan is over, so b.log is new
+	if err := os.WriteFile(filepath.Join(dir, "b.log"), []byte("from b\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("b.log", "from b", func() {})
+
+	// Deleted and recreated within one poll: the new file is attached
+	if err := os.Remove(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(a, []byte("recreated\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	waitFor("a.log", "recreated", func() {})
+}
+
+func TestHistoryBlocksAndIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.log")
+	var b strings.Builder
+	for i := 1; i <= 5000; i++ {
+		fmt.Fprintf(&b, "line %05d\n", i)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	st := NewState()
+	got := collect(t, Options{Source: SourceFile, Paths: []string{path}, BlockSizeBytes: 1000, StartOffset: -1, State: st})
+	if got[0].Text != "line 04911" || got[0].Offset != 4910*11 || got[0].End != 4911*11 {
+		t.Fatalf("first line of block: %+v", got[0])
+	}
+	older, err := ReadBlockBefore(context.Background(), path, got[0].Offset, 100, Options{})
+	if err != nil || len(older) != 9 || older[8].Text != "line 04910" || older[8].End != got[0].Offset {
+		t.Fatalf("older block: %v %+v", err, older)
+	}
+	newer, err := ReadBlockAfter(context.Background(), path, 0, 30, Options{})
+	if err != nil || len(newer) != 3 || newer[2].Text != "line 00003" {
+		t.Fatalf("newer block: %v %+v", err, newer)
+	}
+	if _, ok := st.Index().LineAt(got[0].Offset); ok {
+		t.Fatal("a block read should not number lines it skipped")
+	}
+	st = NewState()
+	got = collect(t, Options{Source: SourceFile, Paths: []string{path}, StartOffset: -1, State: st})
+	ix := st.Index()
+	if n, done := ix.Lines(); !done || n != 5000 || len(got) != 5000 {
+		t.Fatalf("index lines %d done=%v, read %d", n, done, len(got))
+	}
+	if n, ok := ix.LineAt(4910 * 11); !ok || n != 4911 {
+		t.Fatalf("LineAt: %d %v", n, ok)
+	}
+}
+
+func TestTimeRangeSeeksFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "timed.log")
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var b strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&b, "%s line %05d\n", base.Add(time.Duration(i)*time.Second).Format(time.RFC3339), i)
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	timeOf := func(line string) (time.Time, bool) {
+		ts, err := time.Parse(time.RFC3339, strings.SplitN(line, " ", 2)[0])
+		return ts, err == nil
+	}
+	since, until := base.Add(12345*time.Second), base.Add(12400*time.Second)
+	got := collect(t, Options{Source: SourceFile, Paths: []string{path}, Since: since, Until: until, TimeOf: timeOf, State: NewState()})
+	if len(got) != 56 || !strings.HasSuffix(got[0].Text, "line 12345") || !strings.HasSuffix(got[55].Text, "line 12400") {
+		t.Fatalf("range: %d lines, first %+v", len(got), got[0])
+	}
+	off, err := SeekTime(context.Background(), path, func(ts time.Time) bool { return ts.After(base.Add(time.Hour * 24)) }, timeOf, nil)
+	if err != nil || off != int64(b.Len()) {
+		t.Fatalf("seek past end: %d %v", off, err)
+	}
+}
+
+func TestSeekTimeStepsOverUntimedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.log")
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var b strings.Builder
+	var want int64
+	for i := 0; i < 10; i++ {
+		if i == 7 {
+			want = int64(b.Len())
+		}
+		fmt.Fprintf(&b, "%s record %d\n", base.Add(time.Duration(i)*time.Minute).Format(time.RFC3339), i)
+		for j := 0; j < 6000; j++ {
+			b.WriteString("    at frame\n")
+		}
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	timeOf := func(line string) (time.Time, bool) {
+		ts, err := time.Parse(time.RFC3339, strings.SplitN(line, " ", 2)[0])
+		return ts, err == nil
+	}
+	ix := newIndex(path)
+	off, err := SeekTime(context.Background(), path, func(ts time.Time) bool { return !ts.Before(base.Add(7 * time.Minute)) }, timeOf, ix)
+	if err != nil || off != want {
+		t.Fatalf("seek: %d %v, want %d", off, err, want)
+	}
+	if len(ix.times) < 3 {
+		t.Fatalf("bisection sampled only %d timestamps", len(ix.times))
+	}
+}
+
+func TestSinceLastResumesAcrossRotation(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	if err := os.WriteFile(path, []byte("old 1\nold 2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SaveCheckpoint(path, 12); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(f, "new 1")
+	f.Close()
+	got := collect(t, Options{Source: SourceFile, Paths: []string{path}, SinceLast: true})
+	if len(got) != 1 || got[0].Text != "new 1" || got[0].Offset != 12 {
+		t.Fatalf("append: %+v", got)
+	}
+
+	// Rotate: the rest of the old file is read from app.log.1, then the new file
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("fresh 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got = collect(t, Options{Source: SourceFile, Paths: []string{path}, SinceLast: true})
+	if len(got) != 2 || got[0].Text != "new 1" || got[1].Text != "fresh 1" {
+		t.Fatalf("rotation: %+v", got)
+	}
+}
+
+func TestReplayPacesByTimestamp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "replay.log")
+	content := "2025-01-01T00:00:00.000Z a\nno timestamp\n2025-01-01T00:00:00.200Z b\n2025-01-01T00:00:00.400Z c\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	timeOf := func(line string) (time.Time, bool) {
+		ts, err := time.Parse(time.RFC3339Nano, strings.SplitN(line, " ", 2)[0])
+		return ts, err == nil
+	}
+	st := NewState()
+	start := time.Now()
+	got := collect(t, Options{Source: SourceFile, Paths: []string{path}, Replay: 2, TimeOf: timeOf, State: st})
+	if len(got) != 4 {
+		t.Fatalf("got %d lines", len(got))
+	}
+	// 400ms of log time at 2x takes about 200ms
+	if d := got[3].When.Sub(start); d < 180*time.Millisecond || d > 2*time.Second {
+		t.Fatalf("last line sent after %v", d)
+	}
+	if d := got[2].When.Sub(got[1].When); d < 80*time.Millisecond {
+		t.Fatalf("b sent %v after the line before it", d)
+	}
+	if at, speed := st.Replay(); !strings.HasPrefix(got[3].Text, at.Format("2006-01-02T15:04:05.000Z")) || speed != 2 {
+		t.Fatalf("replay state %v %v", at, speed)
+	}
+}
+
+func TestRecordAndLoadCapture(t *testing.T) {
+	dir := t.TempDir()
+	capPath := filepath.Join(dir, "session.lsr")
+	rec, err := CreateRecorder(capPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := collect(t, Options{Source: SourceCommand, Command: "echo out; echo err >&2", ScanBufSize: 1024, Record: rec})
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	loaded := collect(t, Options{Source: SourceCapture, Paths: []string{capPath}})
+	if len(loaded) != len(got) || len(got) != 2 {
+		t.Fatalf("recorded %d lines, loaded %d", len(got), len(loaded))
+	}
+	bySource := map[string]Line{}
+	for _, l := range loaded {
+		bySource[l.Source] = l
+	}
+	for _, l := range got {
+		c := bySource[l.Source]
+		if c.Text != l.Text || !c.When.Equal(l.When) {
+			t.Fatalf("loaded %+v, recorded %+v", c, l)
+		}
+	}
+
+	// A huge length after the magic bytes is an error, not an allocation
+	bad := filepath.Join(dir, "bad.lsr")
+	corrupt := append(append([]byte{}, captureMagic...), 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x7f)
+	if err := os.WriteFile(bad, corrupt, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lines, errs := Read(context.Background(), Options{Source: SourceCapture, Paths: []string{bad}})
+	for range lines {
+	}
+	if err := <-errs; err == nil || !strings.Contains(err.Error(), "capture limit") {
+		t.Fatalf("corrupt capture: %v", err)
+	}
+
+	// A line recorded under a larger line limit is cut to the current one
+	long := filepath.Join(dir, "long.lsr")
+	if rec, err = CreateRecorder(long); err != nil {
+		t.Fatal(err)
+	}
+	rec.Record(Line{Text: strings.Repeat("x", 30) + "é", Source: "stdin", When: time.Now()})
+	rec.Record(Line{Text: "short", Source: "stdin", When: time.Now()})
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	loaded = collect(t, Options{Source: SourceCapture, Paths: []string{long}, ScanBufSize: 31})
+	if len(loaded) != 2 || loaded[0].Text != strings.Repeat("x", 30) || loaded[0].Truncated != 2 || loaded[1].Text != "short" || loaded[1].Truncated != 0 {
+		t.Fatalf("loaded %+v", loaded)
+	}
+}
+
+func TestFollowTruncationAndRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	st := NewState()
+	lines, _ := Read(ctx, Options{Source: SourceFile, Paths: []string{path}, Follow: true, StartOffset: 0, ScanBufSize: 1024, State: st})
+	next := func() Line {
+		select {
+		case l := <-lines:
+			return l
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for a line")
+		}
+		return Line{}
+	}
+	if l := next(); l.Text != "one" {
+		t.Fatalf("got %+v", l)
+	}
+	if l := next(); l.Text != "two" || l.End != 8 {
+		t.Fatalf("got %+v", l)
+	}
+
+	// copytruncate: the file shrinks in place and is written again
+	if err := os.WriteFile(path, []byte("3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if l := next(); !l.Marker || !strings.HasPrefix(l.Text, "file truncated") {
+		t.Fatalf("expected truncation marker, got %+v", l)
+	}
+	if l := next(); l.Text != "3" || l.Offset != 0 {
+		t.Fatalf("got %+v", l)
+	}
+
+	// rename rotation: lines left in the old file come first
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(f, "last of old")
+	f.Close()
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("first of new\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if l := next(); l.Text != "last of old" {
+		t.Fatalf("got %+v", l)
+	}
+	if l := next(); !l.Marker || l.Text != "file rotated" {
+		t.Fatalf("expected rotation marker, got %+v", l)
+	}
+	if l := next(); l.Text != "first of new" {
+		t.Fatalf("got %+v", l)
+	}
+	if n := st.Rotations(); n != 2 {
+		t.Fatalf("rotations = %d", n)
+	}
+}
+
+func TestFollowResumesAtStartOffsets(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte("one\ntwo\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	loaded := collect(t, Options{Source: SourceFile, Paths: []string{path}, ScanBufSize: 1024})
+	end := loaded[len(loaded)-1].End
+
+	// Written between the end of the load and the start of the tail
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprintln(f, "three")
+	f.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, _ := Read(ctx, Options{Source: SourceFile, Paths: []string{path}, Follow: true, StartOffset: -1, StartOffsets: map[string]int64{path: end}, ScanBufSize: 1024})
+	select {
+	case l := <-lines:
+		if l.Text != "three" || l.Offset != end {
+			t.Fatalf("got %+v", l)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out")
+	}
+}
+
+func TestEncodingsTranscodedToUTF8(t *testing.T) {
+	dir := t.TempDir()
+	utf16 := []byte{0xFF, 0xFE}
+	for _, r := range "héllo\r\nwörld\r\n" {
+		utf16 = append(utf16, byte(r), byte(r>>8))
+	}
+	cases := []struct {
+		name, encoding string
+		data           []byte
+		want           []string
+	}{
+		{"utf16-bom.log", "auto", utf16, []string{"héllo", "wörld"}},
+		{"latin1.log", "auto", []byte("caf\xe9 ok\nna\xefve\n"), []string{"café ok", "naïve"}},
+		{"forced.log", "latin1", []byte("\xbfqu\xe9?\n"), []string{"¿qué?"}},
+		{"bom.log", "auto", []byte("\xef\xbb\xbfplain\n"), []string{"plain"}},
+		{"sjis.log", "shift_jis", []byte("\x83\x65\x83\x58\x83\x67\n"), []string{"テスト"}},
+	}
+	for _, c := range cases {
+		path := filepath.Join(dir, c.name)
+		if err := os.WriteFile(path, c.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		got := collect(t, Options{Source: SourceFile, Paths: []string{path}, Encoding: c.encoding, ScanBufSize: 1024})
+		if len(got) != len(c.want) {
+			t.Fatalf("%s: got %+v", c.name, got)
+		}
+		for i, w := range c.want {
+			if got[i].Text != w {
+				t.Fatalf("%s: line %d = %q, want %q", c.name, i, got[i].Text, w)
+			}
+		}
+	}
+
+	// Files of a watched directory are sniffed one by one too
+	got := collect(t, Options{Source: SourceDir, Paths: []string{dir}, Include: "[lu]*.log", ScanBufSize: 1024})
+	bySource := map[string][]string{}
+	for _, l := range got {
+		bySource[l.Source] = append(bySource[l.Source], l.Text)
+	}
+	if strings.Join(bySource["latin1.log"], "|") != "café ok|naïve" || strings.Join(bySource["utf16-bom.log"], "|") != "héllo|wörld" {
+		t.Fatalf("directory: %+v", bySource)
+	}
+	if !IsWideEncoded(filepath.Join(dir, "utf16-bom.log"), "auto") || IsWideEncoded(filepath.Join(dir, "latin1.log"), "auto") {
+		t.Fatal("IsWideEncoded")
+	}
+}
+
+func TestSniffEncoding(t *testing.T) {
+	utf8Text := strings.Repeat("café naïve ", 20)
+	cases := []struct {
+		name string
+		head string
+		want string
+	}{
+		{"latin1", "caf\xe9 ok\nna\xefve\n", encWin1252.name},
+		{"stray byte", "plain ascii\n\xff\nmore ascii\n", ""},
+		{"mostly utf-8", utf8Text + "\xe9\xe8\n", ""},
+		{"cut at the end", "caf\xc3\xa9 " + "na\xc3", ""},
+		{"cut after latin1", "caf\xe9 na\xefve \xf0\x9f", encWin1252.name},
+	}
+	for _, c := range cases {
+		if got := sniffEncoding([]byte(c.head)); got.name != c.want {
+			t.Fatalf("%s: sniffed %q, want %q", c.name, got.name, c.want)
+		}
+	}
+
+	// A forced encoding applies to command output but not to a capture,
+	// whose lines were recorded as UTF-8
+	got := collect(t, Options{Source: SourceCommand, Command: "printf 'caf\\351\\n'", Encoding: "latin1", ScanBufSize: 1024})
+	if len(got) != 1 || got[0].Text != "café" {
+		t.Fatalf("command: %+v", got)
+	}
+	capPath := filepath.Join(t.TempDir(), "utf8.lsr")
+	rec, err := CreateRecorder(capPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec.Record(Line{Text: "café", Source: "cmd", When: time.Now()})
+	if err := rec.Close(); err != nil {
+		t.Fatal(err)
+	}
+	got = collect(t, Options{Source: SourceCapture, Paths: []string{capPath}, Encoding: "latin1", ScanBufSize: 1024})
+	if len(got) != 1 || got[0].Text != "café" {
+		t.Fatalf("capture: %+v", got)
+	}
+}
+
+func TestOversizedLinesTruncated(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "big.log")
+	long := strings.Repeat("x", 5000)
+	if err := os.WriteFile(path, []byte("short\n"+long+"\r\nafter\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	check := func(got []Line) {
+		t.Helper()
+		if len(got) != 3 || got[0].Text != "short" || got[2].Text != "after" {
+			t.Fatalf("got %+v", got)
+		}
+		if got[1].Text != long[:100] || got[1].Truncated != 4900 {
+			t.Fatalf("long line = %d bytes, truncated %d", len(got[1].Text), got[1].Truncated)
+		}
+		if got[1].Offset != 6 || got[1].End != 6+5002 || got[2].Offset != got[1].End {
+			t.Fatalf("offsets = %d-%d, next %d", got[1].Offset, got[1].End, got[2].Offset)
+		}
+	}
+	check(collect(t, Options{Source: SourceFile, Paths: []string{path}, ScanBufSize: 100}))
+
+	// Following cuts the line the same way
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, _ := Read(ctx, Options{Source: SourceFile, Paths: []string{path}, Follow: true, StartOffset: 0, ScanBufSize: 100})
+	got := []Line{}
+	timeout := time.After(5 * time.Second)
+	for len(got) < 3 {
+		select {
+		case l := <-lines:
+			got = append(got, l)
+		case <-timeout:
+			t.Fatalf("follow: got %+v", got)
+		}
+	}
+	check(got)
+
+	// A multi-byte character at the cut is not split
+	if err := os.WriteFile(path, []byte(strings.Repeat("a", 99)+"é"+long+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got = collect(t, Options{Source: SourceFile, Paths: []string{path}, ScanBufSize: 100})
+	if len(got) != 1 || len(got[0].Text) != 99 || got[0].Truncated != 5002 {
+		t.Fatalf("got %d bytes, truncated %d", len(got[0].Text), got[0].Truncated)
+	}
+}
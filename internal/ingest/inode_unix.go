@@ -0,0 +1,17 @@
+//go:build unix
+
+package ingest
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileInode returns the inode number of a file, used to tell a rotated file
+// from the one that replaced it.
+func fileInode(st os.FileInfo) uint64 {
+	if sys, ok := st.Sys().(*syscall.Stat_t); ok {
+		return uint64(sys.Ino)
+	}
+	return 0
+}
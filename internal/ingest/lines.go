@@ -0,0 +1,104 @@
+package ingest
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf8"
+)
+
+// lineTailBytes is how much of the end of each raw line lineReader keeps
+// (see lineReader.tail).
+const lineTailBytes = 64
+
+// lineReader splits input into lines like bufio.ScanLines, but a line longer
+// than max bytes is cut to its first max bytes instead of failing the
+// stream: the rest of it is skipped and reported as dropped. An unterminated
+// line at the end of the input stays pending so following can complete it
+// once more data arrives.
+type lineReader struct {
+	br  *bufio.Reader
+	max int
+	buf []byte // start of the pending line, up to max+2 bytes
+	raw int64  // bytes of input the pending line spans so far
+	// tail is the last bytes of the pending (or last returned) line as read,
+	// end of line included
+	tail []byte
+}
+
+func newLineReader(r io.Reader, max int) *lineReader {
+	if max <= 0 {
+		max = bufio.MaxScanTokenSize
+	}
+	return &lineReader{br: bufio.NewReaderSize(r, 64<<10), max: max}
+}
+
+// next returns the next line without its end of line, how many bytes of
+// input it spans (end of line included) and how many bytes were dropped
+// from it. An unterminated last line is returned as is; io.EOF follows it.
+func (lr *lineReader) next() (text string, raw, dropped int64, err error) {
+	text, raw, dropped, err = lr.readLine()
+	if err == io.EOF && lr.raw > 0 {
+		text, raw, dropped = lr.take()
+		return text, raw, dropped, nil
+	}
+	return text, raw, dropped, err
+}
+
+// readLine is next for input that may still grow: it only returns complete
+// lines and keeps an unterminated one pending when the input ends.
+func (lr *lineReader) readLine() (text string, raw, dropped int64, err error) {
+	for {
+		chunk, err := lr.br.ReadSlice('\n')
+		lr.raw += int64(len(chunk))
+		if room := lr.max + 2 - len(lr.buf); room > 0 {
+			lr.buf = append(lr.buf, chunk[:min(room, len(chunk))]...)
+		}
+		lr.tail = append(lr.tail, chunk[max(0, len(chunk)-lineTailBytes):]...)
+		if n := len(lr.tail); n > lineTailBytes {
+			lr.tail = append(lr.tail[:0], lr.tail[n-lineTailBytes:]...)
+		}
+		switch err {
+		case nil:
+			text, raw, dropped = lr.take()
+			return text, raw, dropped, nil
+		case bufio.ErrBufferFull:
+			continue
+		default:
+			return "", 0, 0, err
+		}
+	}
+}
+
+// take returns the pending line and starts a new one.
+func (lr *lineReader) take() (text string, raw, dropped int64) {
+	content := lr.raw
+	switch {
+	case bytes.HasSuffix(lr.tail, []byte("\r\n")):
+		content -= 2
+	case bytes.HasSuffix(lr.tail, []byte("\n")):
+		content--
+	case bytes.HasSuffix(lr.tail, []byte("\r")):
+		// Like bufio.ScanLines, drop a carriage return ending the input
+		content--
+	}
+	kept := min(content, int64(lr.max), int64(len(lr.buf)))
+	if kept < content {
+		// Do not split a UTF-8 sequence
+		for i := 1; i < utf8.UTFMax && kept > 0 && !utf8.RuneStart(lr.buf[kept]); i++ {
+			kept--
+		}
+	}
+	text, raw, dropped = string(lr.buf[:kept]), lr.raw, content-kept
+	lr.buf, lr.raw = lr.buf[:0], 0
+	return text, raw, dropped
+}
+
+// pending is how many bytes of an unterminated line were read.
+func (lr *lineReader) pending() int64 { return lr.raw }
+
+// reset discards any pending line and reads from r.
+func (lr *lineReader) reset(r io.Reader) {
+	lr.br.Reset(r)
+	lr.buf, lr.raw, lr.tail = lr.buf[:0], 0, lr.tail[:0]
+}
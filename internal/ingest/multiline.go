@@ -0,0 +1,197 @@
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Built-in multiline rules. A physical line that matches any enabled rule is
+// treated as a continuation of the previous record from the same source.
+const (
+	MultilineIndent    = "indent"    // leading whitespace (stack frames, wrapped text)
+	MultilineCausedBy  = "caused-by" // "Caused by:", Python tracebacks, "... N more"
+	MultilineTimestamp = "timestamp" // lines without a timestamp prefix
+)
+
+var (
+	reCausedBy        = regexp.MustCompile(`^(?:Caused by:|Suppressed:|Traceback \(most recent call last\):|During handling of the above exception|The above exception was the direct cause|\.\.\. \d+ (?:more|common frames omitted)|[A-Za-z_$][\w$]*(?:\.[A-Za-z_$][\w$]*)*(?:Error|Exception|Throwable)(?::|$))`)
+	reTimestampPrefix = regexp.MustCompile(`^(?:\[|\{"(?:ts|time|timestamp|@timestamp)":")?(?:\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}|\d{4}/\d{2}/\d{2} \d{2}:\d{2}|\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}|[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}|\d{2}:\d{2}:\d{2}|<\d{1,3}>|(?:ts|time|timestamp)=)`)
+)
+
+// MultilineOptions configures the multiline assembly stage.
+type MultilineOptions struct {
+	// Rules lists built-in continuation rules (see Multiline* constants).
+	Rules []string
+	// Start, when set, marks the first line of a record; every line that does
+	// not match it is a continuation. It takes precedence over Rules.
+	Start *regexp.Regexp
+	// Timeout flushes a pending record when no continuation arrives in time,
+	// so the last record shows up promptly in follow mode.
+	Timeout time.Duration
+	// MaxLines caps the number of physical lines joined into one record.
+	MaxLines int
+}
+
+// ParseMultilineRules validates a comma-separated rule list. "auto" enables
+// the indent and caused-by rules; "off" or "" disables multiline assembly.
+func ParseMultilineRules(spec string) ([]string, error) {
+	spec = strings.TrimSpace(strings.ToLower(spec))
+	if spec == "" || spec == "off" || spec == "none" {
+		return nil, nil
+	}
+	rules := []string{}
+	for _, r := range strings.Split(spec, ",") {
+		r = strings.TrimSpace(r)
+		switch r {
+		case "":
+		case "auto":
+			rules = append(rules, MultilineIndent, MultilineCausedBy)
+		case MultilineIndent, MultilineCausedBy, MultilineTimestamp:
+			rules = append(rules, r)
+		default:
+			return nil, fmt.Errorf("unknown multiline rule %q (use indent, caused-by, timestamp or auto)", r)
+		}
+	}
+	return rules, nil
+}
+
+func (o MultilineOptions) enabled() bool {
+	return o.Start != nil || len(o.Rules) > 0
+}
+
+func (o MultilineOptions) isContinuation(text string) bool {
+	if o.Start != nil {
+		return !o.Start.MatchString(text)
+	}
+	for _, r := range o.Rules {
+		switch r {
+		case MultilineIndent:
+			if strings.HasPrefix(text, " ") || strings.HasPrefix(text, "\t") {
+				return true
+			}
+		case MultilineCausedBy:
+			if reCausedBy.MatchString(text) {
+				return true
+			}
+		case MultilineTimestamp:
+			if !reTimestampPrefix.MatchString(text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type pendingRecord struct {
+	first     Line
+	lines     []string
+	end       int64
+	truncated int64
+	last      time.Time
+}
+
+func (p *pendingRecord) line() Line {
+	l := p.first
+	l.Text = strings.Join(p.lines, "\n")
+	l.End = p.end
+	l.Truncated = p.truncated
+	return l
+}
+
+// multiline joins continuation lines into the preceding record of the same
+// source. Records are emitted when the next record starts, when the timeout
+// elapses or when the input closes.
+func multiline(ctx context.Context, in <-chan Line, opt MultilineOptions) <-chan Line {
+	out := make(chan Line, cap(in))
+	timeout := opt.Timeout
+	if timeout <= 0 {
+		timeout = time.Second
+	}
+	maxLines := opt.MaxLines
+	if maxLines <= 0 {
+		maxLines = 500
+	}
+	go func() {
+		defer close(out)
+		pending := map[string]*pendingRecord{}
+		emit := func(src string) bool {
+			p := pending[src]
+			if p == nil {
+				return true
+			}
+			delete(pending, src)
+			select {
+			case out <- p.line():
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		flushAll := func() {
+			srcs := make([]string, 0, len(pending))
+			for s := range pending {
+				srcs = append(srcs, s)
+			}
+			sort.Slice(srcs, func(i, j int) bool { return pending[srcs[i]].first.When.Before(pending[srcs[j]].first.When) })
+			for _, s := range srcs {
+				if !emit(s) {
+					return
+				}
+			}
+		}
+		tick := timeout / 2
+		if tick < 50*time.Millisecond {
+			tick = 50 * time.Millisecond
+		}
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case l, ok := <-in:
+				if !ok {
+					flushAll()
+					return
+				}
+				if l.Marker {
+					// Never join a reader notice into a record
+					if !emit(l.Source) {
+						return
+					}
+					select {
+					case out <- l:
+					case <-ctx.Done():
+						return
+					}
+					continue
+				}
+				now := time.Now()
+				if p := pending[l.Source]; p != nil && len(p.lines) < maxLines && opt.isContinuation(l.Text) {
+					p.lines = append(p.lines, l.Text)
+					p.end = l.End
+					p.truncated += l.Truncated
+					p.last = now
+					continue
+				}
+				if !emit(l.Source) {
+					return
+				}
+				pending[l.Source] = &pendingRecord{first: l, lines: []string{l.Text}, end: l.End, truncated: l.Truncated, last: now}
+			case now := <-ticker.C:
+				for s, p := range pending {
+					if now.Sub(p.last) >= timeout {
+						if !emit(s) {
+							return
+						}
+					}
+				}
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,522 @@
+package ingest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// otlpRecord is a decoded OTLP LogRecord together with the attributes of the
+// resource and scope it was sent under.
+type otlpRecord struct {
+	timeUnixNano     uint64
+	observedUnixNano uint64
+	severityNumber   int64
+	severityText     string
+	body             any
+	attrs            map[string]any
+	resourceAttrs    map[string]any
+	scope            string
+	traceID          string
+	spanID           string
+}
+
+// line renders the record as a flat JSON object that the JSON parser maps
+// onto LogEntry: ts, level and msg plus resource and record attributes.
+func (r otlpRecord) line() string {
+	m := map[string]any{}
+	for k, v := range r.resourceAttrs {
+		m[k] = v
+	}
+	for k, v := range r.attrs {
+		m[k] = v
+	}
+	ns := r.timeUnixNano
+	if ns == 0 {
+		ns = r.observedUnixNano
+	}
+	if ns != 0 {
+		m["ts"] = time.Unix(0, int64(ns)).UTC().Format(time.RFC3339Nano)
+	}
+	if lvl := otlpLevel(r.severityText, r.severityNumber); lvl != "" {
+		m["level"] = lvl
+	}
+	if r.body != nil {
+		m["msg"] = r.body
+	}
+	if r.scope != "" {
+		m["scope"] = r.scope
+	}
+	if r.traceID != "" {
+		m["trace_id"] = r.traceID
+	}
+	if r.spanID != "" {
+		m["span_id"] = r.spanID
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+// source tags a record with its service.name when the resource carries one.
+func (r otlpRecord) source(fallback string) string {
+	if s, ok := r.resourceAttrs["service.name"].(string); ok && s != "" {
+		return s
+	}
+	return fallback
+}
+
+// otlpLevel prefers the severity text and otherwise maps the severity number
+// ranges defined by the OpenTelemetry log data model.
+func otlpLevel(text string, num int64) string {
+	if strings.TrimSpace(text) != "" {
+		return text
+	}
+	switch {
+	case num <= 0:
+		return ""
+	case num <= 4:
+		return "TRACE"
+	case num <= 8:
+		return "DEBUG"
+	case num <= 12:
+		return "INFO"
+	case num <= 16:
+		return "WARN"
+	case num <= 20:
+		return "ERROR"
+	default:
+		return "FATAL"
+	}
+}
+
+// otlpLogsHandler implements the OTLP/HTTP logs endpoint (POST /v1/logs) for
+// both JSON and protobuf encodings.
+func otlpLogsHandler(ctx context.Context, out chan<- Line) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/logs", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", "POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := requestBody(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer body.Close()
+		data, err := io.ReadAll(body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(data) > maxHTTPBody {
+			http.Error(w, "body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		ct, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		isJSON := ct == "application/json"
+		var recs []otlpRecord
+		if isJSON {
+			recs, err = decodeOTLPJSON(data)
+		} else {
+			recs, err = decodeOTLPProto(data)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		fallback := requestSource(r)
+		now := time.Now()
+		for _, rec := range recs {
+			select {
+			case out <- Line{Text: rec.line(), Source: rec.source(fallback), When: now}:
+			case <-ctx.Done():
+				http.Error(w, "shutting down", http.StatusServiceUnavailable)
+				return
+			case <-r.Context().Done():
+				return
+			}
+		}
+		// An empty ExportLogsServiceResponse signals full success.
+		if isJSON {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte("{}"))
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// --- OTLP/JSON ---
+
+type otlpJSONRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpJSONKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Name string `json:"name"`
+			} `json:"scope"`
+			LogRecords []struct {
+				TimeUnixNano         json.RawMessage    `json:"timeUnixNano"`
+				ObservedTimeUnixNano json.RawMessage    `json:"observedTimeUnixNano"`
+				SeverityNumber       json.RawMessage    `json:"severityNumber"`
+				SeverityText         string             `json:"severityText"`
+				Body                 *otlpJSONAnyValue  `json:"body"`
+				Attributes           []otlpJSONKeyValue `json:"attributes"`
+				TraceID              string             `json:"traceId"`
+				SpanID               string             `json:"spanId"`
+			} `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type otlpJSONKeyValue struct {
+	Key   string           `json:"key"`
+	Value otlpJSONAnyValue `json:"value"`
+}
+
+type otlpJSONAnyValue struct {
+	StringValue *string         `json:"stringValue"`
+	BoolValue   *bool           `json:"boolValue"`
+	IntValue    json.RawMessage `json:"intValue"`
+	DoubleValue *float64        `json:"doubleValue"`
+	BytesValue  *string         `json:"bytesValue"`
+	ArrayValue  *struct {
+		Values []otlpJSONAnyValue `json:"values"`
+	} `json:"arrayValue"`
+	KvlistValue *struct {
+		Values []otlpJSONKeyValue `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+func (v otlpJSONAnyValue) value() any {
+	switch {
+	case v.StringValue != nil:
+		return *v.StringValue
+	case v.BoolValue != nil:
+		return *v.BoolValue
+	case len(v.IntValue) > 0:
+		n, _ := jsonInt(v.IntValue)
+		return n
+	case v.DoubleValue != nil:
+		return *v.DoubleValue
+	case v.BytesValue != nil:
+		return *v.BytesValue
+	case v.ArrayValue != nil:
+		arr := make([]any, 0, len(v.ArrayValue.Values))
+		for _, it := range v.ArrayValue.Values {
+			arr = append(arr, it.value())
+		}
+		return arr
+	case v.KvlistValue != nil:
+		m := map[string]any{}
+		for _, kv := range v.KvlistValue.Values {
+			m[kv.Key] = kv.Value.value()
+		}
+		return m
+	}
+	return nil
+}
+
+// jsonInt accepts 64-bit integers encoded either as JSON numbers or, as the
+// OTLP/JSON mapping requires for (u)int64, as decimal strings.
+func jsonInt(raw json.RawMessage) (int64, error) {
+	s := strings.Trim(strings.TrimSpace(string(raw)), `"`)
+	if s == "" || s == "null" {
+		return 0, nil
+	}
+	return strconv.ParseInt(s, 10, 64)
+}
+
+func jsonUint(raw json.RawMessage) uint64 {
+	s := strings.Trim(strings.TrimSpace(string(raw)), `"`)
+	n, _ := strconv.ParseUint(s, 10, 64)
+	return n
+}
+
+func decodeOTLPJSON(data []byte) ([]otlpRecord, error) {
+	var req otlpJSONRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return nil, err
+	}
+	out := []otlpRecord{}
+	for _, rl := range req.ResourceLogs {
+		res := map[string]any{}
+		for _, kv := range rl.Resource.Attributes {
+			flattenInto(res, kv.Key, kv.Value.value())
+		}
+		for _, sl := range rl.ScopeLogs {
+			for _, lr := range sl.LogRecords {
+				rec := otlpRecord{
+					timeUnixNano:     jsonUint(lr.TimeUnixNano),
+					observedUnixNano: jsonUint(lr.ObservedTimeUnixNano),
+					severityText:     lr.SeverityText,
+					attrs:            map[string]any{},
+					resourceAttrs:    res,
+					scope:            sl.Scope.Name,
+					traceID:          lr.TraceID,
+					spanID:           lr.SpanID,
+				}
+				rec.severityNumber, _ = jsonInt(lr.SeverityNumber)
+				if lr.Body != nil {
+					rec.body = lr.Body.value()
+				}
+				for _, kv := range lr.Attributes {
+					flattenInto(rec.attrs, kv.Key, kv.Value.value())
+				}
+				out = append(out, rec)
+			}
+		}
+	}
+	return out, nil
+}
+
+// flattenInto stores v under key, expanding nested key/value lists into
+// dotted keys so each attribute can become its own column.
+func flattenInto(dst map[string]any, key string, v any) {
+	if m, ok := v.(map[string]any); ok {
+		for k, sub := range m {
+			flattenInto(dst, key+"."+k, sub)
+		}
+		return
+	}
+	dst[key] = v
+}
+
+// --- OTLP/protobuf ---
+//
+// The messages are decoded by field number with protowire, following
+// opentelemetry/proto/collector/logs/v1 and opentelemetry/proto/logs/v1.
+
+var errOTLPProto = errors.New("malformed OTLP protobuf payload")
+
+// protoFields walks the top-level fields of a message.
+func protoFields(b []byte, fn func(num protowire.Number, typ protowire.Type, v []byte, u uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return errOTLPProto
+		}
+		b = b[n:]
+		var (
+			v []byte
+			u uint64
+		)
+		switch typ {
+		case protowire.VarintType:
+			u, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			u, n = protowire.ConsumeFixed64(b)
+		case protowire.Fixed32Type:
+			var u32 uint32
+			u32, n = protowire.ConsumeFixed32(b)
+			u = uint64(u32)
+		case protowire.BytesType:
+			v, n = protowire.ConsumeBytes(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return errOTLPProto
+		}
+		b = b[n:]
+		if err := fn(num, typ, v, u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func decodeOTLPProto(data []byte) ([]otlpRecord, error) {
+	out := []otlpRecord{}
+	err := protoFields(data, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if num != 1 || typ != protowire.BytesType { // resource_logs
+			return nil
+		}
+		recs, err := decodeProtoResourceLogs(v)
+		out = append(out, recs...)
+		return err
+	})
+	return out, err
+}
+
+func decodeProtoResourceLogs(b []byte) ([]otlpRecord, error) {
+	res := map[string]any{}
+	scopes := [][]byte{}
+	err := protoFields(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		if typ != protowire.BytesType {
+			return nil
+		}
+		switch num {
+		case 1: // resource
+			return protoFields(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType { // attributes
+					k, val, err := decodeProtoKeyValue(v)
+					if err != nil {
+						return err
+					}
+					flattenInto(res, k, val)
+				}
+				return nil
+			})
+		case 2: // scope_logs
+			scopes = append(scopes, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := []otlpRecord{}
+	for _, sb := range scopes {
+		scope := ""
+		err := protoFields(sb, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+			if typ != protowire.BytesType {
+				return nil
+			}
+			switch num {
+			case 1: // scope
+				return protoFields(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+					if num == 1 && typ == protowire.BytesType {
+						scope = string(v)
+					}
+					return nil
+				})
+			case 2: // log_records
+				rec, err := decodeProtoLogRecord(v)
+				if err != nil {
+					return err
+				}
+				rec.resourceAttrs = res
+				rec.scope = scope
+				out = append(out, rec)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+func decodeProtoLogRecord(b []byte) (otlpRecord, error) {
+	rec := otlpRecord{attrs: map[string]any{}}
+	err := protoFields(b, func(num protowire.Number, typ protowire.Type, v []byte, u uint64) error {
+		switch num {
+		case 1:
+			rec.timeUnixNano = u
+		case 11:
+			rec.observedUnixNano = u
+		case 2:
+			rec.severityNumber = int64(u)
+		case 3:
+			rec.severityText = string(v)
+		case 5:
+			val, err := decodeProtoAnyValue(v)
+			if err != nil {
+				return err
+			}
+			rec.body = val
+		case 6:
+			k, val, err := decodeProtoKeyValue(v)
+			if err != nil {
+				return err
+			}
+			flattenInto(rec.attrs, k, val)
+		case 9:
+			rec.traceID = hex.EncodeToString(v)
+		case 10:
+			rec.spanID = hex.EncodeToString(v)
+		}
+		return nil
+	})
+	return rec, err
+}
+
+func decodeProtoKeyValue(b []byte) (string, any, error) {
+	var key string
+	var val any
+	err := protoFields(b, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+		switch num {
+		case 1:
+			key = string(v)
+		case 2:
+			x, err := decodeProtoAnyValue(v)
+			if err != nil {
+				return err
+			}
+			val = x
+		}
+		return nil
+	})
+	return key, val, err
+}
+
+func decodeProtoAnyValue(b []byte) (any, error) {
+	var val any
+	err := protoFields(b, func(num protowire.Number, typ protowire.Type, v []byte, u uint64) error {
+		switch num {
+		case 1:
+			val = string(v)
+		case 2:
+			val = u != 0
+		case 3:
+			val = int64(u)
+		case 4:
+			val = math.Float64frombits(u)
+		case 5: // array_value
+			arr := []any{}
+			err := protoFields(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					x, err := decodeProtoAnyValue(v)
+					if err != nil {
+						return err
+					}
+					arr = append(arr, x)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			val = arr
+		case 6: // kvlist_value
+			m := map[string]any{}
+			err := protoFields(v, func(num protowire.Number, typ protowire.Type, v []byte, _ uint64) error {
+				if num == 1 && typ == protowire.BytesType {
+					k, x, err := decodeProtoKeyValue(v)
+					if err != nil {
+						return err
+					}
+					m[k] = x
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			val = m
+		case 7:
+			val = base64.StdEncoding.EncodeToString(v)
+		}
+		return nil
+	})
+	return val, err
+}
@@ -0,0 +1,47 @@
+package ingest
+
+import (
+	"context"
+	"time"
+)
+
+// replay re-emits records on the schedule given by their clock times (parsed
+// timestamps or arrival times), scaled by speed (2 = twice as fast): a record
+// stamped d after the first one is sent d/speed after it. Records without a
+// timestamp, or stamped before the replay clock (out of order), are sent
+// right away. When is reset to the emission time so rates look live.
+func replay(ctx context.Context, in <-chan Line, speed float64, clock func(Line) (time.Time, bool), st *State) <-chan Line {
+	out := make(chan Line, cap(in))
+	go func() {
+		defer close(out)
+		var first time.Time // timestamp of the first stamped record
+		var start time.Time // wall time it was sent
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		<-timer.C
+		for l := range in {
+			if ts, ok := clock(l); ok {
+				if first.IsZero() {
+					first, start = ts, time.Now()
+				}
+				due := start.Add(time.Duration(float64(ts.Sub(first)) / speed))
+				if wait := time.Until(due); wait > 0 {
+					timer.Reset(wait)
+					select {
+					case <-timer.C:
+					case <-ctx.Done():
+						return
+					}
+				}
+				st.setReplay(ts, speed)
+			}
+			l.When = time.Now()
+			select {
+			case out <- l:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
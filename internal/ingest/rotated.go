@@ -0,0 +1,141 @@
+package ingest
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// app.log.1, app.log.2.gz
+	reRotatedNumeric = regexp.MustCompile(`^\.(\d{1,6})(?:\.(?:gz|gzip|bz2|zst|zstd))?$`)
+	// app.log-20250101, app.log.2025-01-01.gz, app.log-2025010112
+	reRotatedDated = regexp.MustCompile(`^[.-](\d{4}-?\d{2}-?\d{2}(?:[-_T]?\d{2,6})?)(?:\.(?:gz|gzip|bz2|zst|zstd))?$`)
+)
+
+// rotatedSiblings returns the logrotate siblings of base (numeric and dated
+// suffixes, compressed or not) ordered oldest-first. The live file itself is
+// not included.
+func rotatedSiblings(base string) ([]string, error) {
+	dir, name := filepath.Split(base)
+	if dir == "" {
+		dir = "."
+	}
+	ents, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	type dated struct {
+		path string
+		key  string
+	}
+	type numbered struct {
+		path string
+		n    int
+	}
+	var ds []dated
+	var ns []numbered
+	for _, e := range ents {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), name) || e.Name() == name {
+			continue
+		}
+		suffix := e.Name()[len(name):]
+		p := filepath.Join(dir, e.Name())
+		if m := reRotatedNumeric.FindStringSubmatch(suffix); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			ns = append(ns, numbered{path: p, n: n})
+			continue
+		}
+		if m := reRotatedDated.FindStringSubmatch(suffix); m != nil {
+			key := strings.Map(func(r rune) rune {
+				if r >= '0' && r <= '9' {
+					return r
+				}
+				return -1
+			}, m[1])
+			ds = append(ds, dated{path: p, key: key})
+		}
+	}
+	// Dated archives sort by date; numeric ones are newest at .1.
+	sort.Slice(ds, func(i, j int) bool { return ds[i].key < ds[j].key })
+	sort.Slice(ns, func(i, j int) bool { return ns[i].n > ns[j].n })
+	out := make([]string, 0, len(ds)+len(ns))
+	for _, d := range ds {
+		out = append(out, d.path)
+	}
+	for _, n := range ns {
+		out = append(out, n.path)
+	}
+	return out, nil
+}
+
+// readRotated streams every rotated sibling of base oldest-first, then the
+// live file. With follow, it keeps tailing the live file from where the
+// initial read stopped so no line is read twice.
+func readRotated(ctx context.Context, base string, opt Options, encs *encodingSet, out chan<- Line, errs chan<- error) {
+	live := sniffFile(base, base, opt.Encoding, encs)
+	if off, ok := opt.StartOffsets[base]; ok && opt.Follow && !live.wide {
+		// The siblings and the start of the live file were read before
+		readFromTail(ctx, base, base, off, true, opt.ScanBufSize, opt.State, out, errs)
+		return
+	}
+	siblings, err := rotatedSiblings(base)
+	if err != nil {
+		errs <- err
+		return
+	}
+	for _, p := range siblings {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+		rc, _, err := openInput(p)
+		if err != nil {
+			errs <- err
+			continue
+		}
+		te := sniffFile(p, p, opt.Encoding, encs)
+		readFromReader(ctx, te.reader(rc), p, opt.ScanBufSize, out, errs)
+		rc.Close()
+	}
+	whole := opt
+	whole.BlockSizeBytes = 0
+	if live.wide {
+		readWideFile(ctx, base, base, live, whole, out, errs)
+		return
+	}
+	if !opt.Follow {
+		readFile(ctx, base, whole, -1, out, errs)
+		return
+	}
+	f, err := os.Open(base)
+	if err != nil {
+		errs <- err
+		return
+	}
+	cr := &countingReader{r: f}
+	readFromReader(ctx, cr, base, opt.ScanBufSize, out, errs)
+	f.Close()
+	if ctx.Err() != nil {
+		return
+	}
+	readFromTail(ctx, base, base, cr.n, true, opt.ScanBufSize, opt.State, out, errs)
+}
+
+// countingReader tracks how many bytes have been consumed from r.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
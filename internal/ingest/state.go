@@ -0,0 +1,154 @@
+package ingest
+
+import (
+	"sync"
+	"time"
+)
+
+// State is shared between a running ingest and the UI: ingest goroutines
+// record what they are doing and the UI reads it to render the status bar.
+// A nil *State is valid and ignores updates.
+type State struct {
+	mu        sync.Mutex
+	process   string
+	restarts  int
+	supervise bool
+	// superviseOn is closed when supervision is switched back on
+	superviseOn chan struct{}
+	index       *Index
+	rotations   int
+	// replayAt is the timestamp of the last record sent by a replay
+	replayAt    time.Time
+	replaySpeed float64
+}
+
+func NewState() *State {
+	return &State{supervise: true}
+}
+
+// Process returns the supervised command state (empty when no command source
+// is running) and how many times the command was restarted.
+func (s *State) Process() (string, int) {
+	if s == nil {
+		return "", 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.process, s.restarts
+}
+
+func (s *State) setProcess(state string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.process = state
+	s.mu.Unlock()
+}
+
+func (s *State) addRestart() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.restarts++
+	s.mu.Unlock()
+}
+
+// SetSupervise controls whether a command source is restarted when it exits.
+func (s *State) SetSupervise(on bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.supervise = on
+	if on && s.superviseOn != nil {
+		close(s.superviseOn)
+		s.superviseOn = nil
+	}
+	s.mu.Unlock()
+}
+
+// superviseResumed returns a channel closed once supervision is on.
+func (s *State) superviseResumed() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.superviseOn == nil {
+		s.superviseOn = make(chan struct{})
+		if s.supervise {
+			close(s.superviseOn)
+			ch := s.superviseOn
+			s.superviseOn = nil
+			return ch
+		}
+	}
+	return s.superviseOn
+}
+
+func (s *State) supervising() bool {
+	if s == nil {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.supervise
+}
+
+// Index returns the byte-offset index of the file being read, or nil when
+// the source is not a single plain file read without follow.
+func (s *State) Index() *Index {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.index
+}
+
+func (s *State) setIndex(ix *Index) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.index = ix
+	s.mu.Unlock()
+}
+
+// Replay returns the timestamp of the last record re-emitted by a replay and
+// its speed; the time is zero when no replay is running.
+func (s *State) Replay() (time.Time, float64) {
+	if s == nil {
+		return time.Time{}, 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.replayAt, s.replaySpeed
+}
+
+func (s *State) setReplay(at time.Time, speed float64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.replayAt, s.replaySpeed = at, speed
+	s.mu.Unlock()
+}
+
+// Rotations returns how many times a followed file was rotated or truncated.
+func (s *State) Rotations() int {
+	if s == nil {
+		return 0
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rotations
+}
+
+func (s *State) addRotation() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.rotations++
+	s.mu.Unlock()
+}
@@ -0,0 +1,203 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxSyslogDatagram = 64 * 1024
+
+// parseListenAddr splits a listen URL such as udp://127.0.0.1:5514 or
+// unix:///run/logsense.sock into a network and an address.
+func parseListenAddr(raw string) (network, addr string, err error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return "", "", fmt.Errorf("invalid listen address %q (want udp://host:port, tcp://host:port or unix:///path)", raw)
+	}
+	switch u.Scheme {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+		if u.Host == "" {
+			return "", "", fmt.Errorf("missing host:port in %q", raw)
+		}
+		return u.Scheme, u.Host, nil
+	case "unix", "unixgram":
+		p := u.Path
+		if p == "" {
+			p = u.Opaque
+		}
+		if p == "" {
+			return "", "", fmt.Errorf("missing socket path in %q", raw)
+		}
+		return u.Scheme, p, nil
+	}
+	return "", "", fmt.Errorf("unsupported scheme %q in %q", u.Scheme, raw)
+}
+
+// readFromSyslog receives syslog messages (RFC3164 or RFC5424) on the given
+// listen URL. Each message becomes one line tagged with the peer address;
+// newline-framed messages longer than maxLine bytes are truncated.
+func readFromSyslog(ctx context.Context, listen string, maxLine int, out chan<- Line, errs chan<- error) {
+	network, addr, err := parseListenAddr(listen)
+	if err != nil {
+		errs <- err
+		return
+	}
+	if network == "unix" || network == "unixgram" {
+		removeStaleSocket(addr)
+	}
+	switch network {
+	case "udp", "udp4", "udp6", "unixgram":
+		pc, err := net.ListenPacket(network, addr)
+		if err != nil {
+			errs <- err
+			return
+		}
+		go func() {
+			<-ctx.Done()
+			pc.Close()
+		}()
+		readSyslogPackets(ctx, pc, addr, out, errs)
+	default:
+		ln, err := net.Listen(network, addr)
+		if err != nil {
+			errs <- err
+			return
+		}
+		go func() {
+			<-ctx.Done()
+			ln.Close()
+		}()
+		readSyslogStreams(ctx, ln, addr, maxLine, out, errs)
+	}
+}
+
+// removeStaleSocket deletes a leftover unix socket file from a previous run.
+func removeStaleSocket(path string) {
+	if st, err := os.Lstat(path); err == nil && st.Mode()&os.ModeSocket != 0 {
+		_ = os.Remove(path)
+	}
+}
+
+func readSyslogPackets(ctx context.Context, pc net.PacketConn, local string, out chan<- Line, errs chan<- error) {
+	buf := make([]byte, maxSyslogDatagram)
+	for {
+		n, peer, err := pc.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				errs <- err
+			}
+			return
+		}
+		msg := strings.TrimRight(string(buf[:n]), "\r\n\x00")
+		if msg == "" {
+			continue
+		}
+		src := local
+		if peer != nil && peer.String() != "" {
+			src = peer.String()
+		}
+		select {
+		case out <- Line{Text: msg, Source: src, When: time.Now()}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func readSyslogStreams(ctx context.Context, ln net.Listener, local string, maxLine int, out chan<- Line, errs chan<- error) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				errs <- err
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			done := make(chan struct{})
+			defer close(done)
+			defer conn.Close()
+			go func() {
+				select {
+				case <-ctx.Done():
+					conn.Close()
+				case <-done:
+				}
+			}()
+			src := local
+			if ra := conn.RemoteAddr(); ra != nil && ra.String() != "" {
+				src = ra.String()
+			}
+			readSyslogFrames(ctx, newLineReader(conn, maxLine), src, out)
+		}()
+	}
+}
+
+// readSyslogFrames reads messages from a stream connection, handling both
+// octet-counted framing ("<len> <msg>", RFC6587 3.4.1) and newline-delimited
+// framing. The framing is decided per message from its first byte.
+func readSyslogFrames(ctx context.Context, lr *lineReader, src string, out chan<- Line) {
+	br := lr.br
+	for {
+		var dropped int64
+		b, err := br.Peek(1)
+		if err != nil {
+			return
+		}
+		var msg string
+		if b[0] >= '0' && b[0] <= '9' {
+			// The length prefix is read byte by byte so a peer that never
+			// sends the space cannot make it grow
+			n := 0
+			for digits := 0; ; digits++ {
+				c, err := br.ReadByte()
+				if err != nil {
+					return
+				}
+				if c == ' ' && digits > 0 {
+					break
+				}
+				if c < '0' || c > '9' || digits >= len(strconv.Itoa(maxSyslogDatagram)) {
+					return
+				}
+				n = n*10 + int(c-'0')
+			}
+			if n > maxSyslogDatagram {
+				return
+			}
+			frame := make([]byte, n)
+			if _, err := io.ReadFull(br, frame); err != nil {
+				return
+			}
+			msg = string(frame)
+		} else {
+			s, _, d, err := lr.next()
+			if err != nil {
+				return
+			}
+			msg, dropped = s, d
+		}
+		msg = strings.TrimRight(msg, "\r\n\x00")
+		if msg == "" {
+			continue
+		}
+		select {
+		case out <- Line{Text: msg, Source: src, When: time.Now(), Truncated: dropped}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
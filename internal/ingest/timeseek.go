@@ -0,0 +1,196 @@
+package ingest
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// TimeFunc extracts the timestamp of a record; ok is false when it has none.
+type TimeFunc func(line string) (t time.Time, ok bool)
+
+// seekWindow is the span below which SeekTime stops bisecting and scans.
+const seekWindow = 64 << 10
+
+type timePoint struct {
+	offset int64
+	ts     time.Time
+}
+
+// SetTimeFunc makes the background index also record the timestamp of each
+// checkpoint line, so later time seeks start from a narrower range.
+func (ix *Index) SetTimeFunc(f TimeFunc) {
+	if ix == nil {
+		return
+	}
+	ix.mu.Lock()
+	ix.timeOf = f
+	ix.mu.Unlock()
+}
+
+func (ix *Index) addTime(offset int64, ts time.Time) {
+	if ix == nil {
+		return
+	}
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	i := sort.Search(len(ix.times), func(i int) bool { return ix.times[i].offset >= offset })
+	if i < len(ix.times) && ix.times[i].offset == offset {
+		return
+	}
+	ix.times = append(ix.times, timePoint{})
+	copy(ix.times[i+1:], ix.times[i:])
+	ix.times[i] = timePoint{offset: offset, ts: ts}
+}
+
+// bracket narrows [0, size) to the known time points around the first
+// record for which pred holds.
+func (ix *Index) bracket(pred func(time.Time) bool, size int64) (lo, hi int64) {
+	hi = size
+	if ix == nil {
+		return 0, hi
+	}
+	ix.mu.RLock()
+	defer ix.mu.RUnlock()
+	for _, p := range ix.times {
+		if pred(p.ts) {
+			if p.offset < hi {
+				hi = p.offset
+			}
+			break
+		}
+		lo = p.offset
+	}
+	if lo > hi {
+		lo = 0
+	}
+	return lo, hi
+}
+
+// SeekTime returns the offset of the first line of path whose timestamp
+// satisfies pred, assuming timestamps grow through the file: pred must be
+// false for early records and true from some point on (e.g. "not before
+// since"). It bisects on byte offsets, reading only a few lines per probe,
+// and returns the file size when no record matches. Probed timestamps are
+// remembered in ix (which may be nil).
+func SeekTime(ctx context.Context, path string, pred func(time.Time) bool, timeOf TimeFunc, ix *Index) (int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := st.Size()
+	lo, hi := ix.bracket(pred, size)
+	for hi-lo > seekWindow {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		mid := lo + (hi-lo)/2
+		start, ts, ok := probeTime(f, mid, hi, size, timeOf)
+		if !ok {
+			// No timestamped line starts in [mid, hi), so none of them
+			// can be the first match past lo
+			hi = mid
+			continue
+		}
+		ix.addTime(start, ts)
+		if pred(ts) {
+			hi = start
+		} else {
+			lo = start
+		}
+	}
+	// Linear scan of the remaining window, continuing past it if needed.
+	br := bufio.NewReaderSize(io.NewSectionReader(f, lo, size-lo), 64<<10)
+	pos := lo
+	for {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			if ts, ok := timeOf(trimEOL(line)); ok && pred(ts) {
+				return pos, nil
+			}
+			pos += int64(len(line))
+		}
+		if err != nil {
+			return size, nil
+		}
+	}
+}
+
+// probeTime finds the first line starting in [off, end) that carries a
+// timestamp and returns its offset. Lines without one (continuation lines,
+// banners) are stepped over.
+func probeTime(f *os.File, off, end, size int64, timeOf TimeFunc) (int64, time.Time, bool) {
+	start := off
+	if start > 0 {
+		start--
+	}
+	br := bufio.NewReaderSize(io.NewSectionReader(f, start, size-start), 64<<10)
+	pos := start
+	if off > 0 {
+		// Skip to the first line start at or after off
+		skipped, err := br.ReadString('\n')
+		pos += int64(len(skipped))
+		if err != nil {
+			return 0, time.Time{}, false
+		}
+	}
+	for pos < end {
+		line, err := br.ReadString('\n')
+		if len(line) > 0 {
+			if ts, ok := timeOf(trimEOL(line)); ok {
+				return pos, ts, true
+			}
+			pos += int64(len(line))
+		}
+		if err != nil {
+			break
+		}
+	}
+	return 0, time.Time{}, false
+}
+
+func trimEOL(s string) string {
+	if n := len(s); n > 0 && s[n-1] == '\n' {
+		s = s[:n-1]
+		if n := len(s); n > 0 && s[n-1] == '\r' {
+			s = s[:n-1]
+		}
+	}
+	return s
+}
+
+// HeadLines returns up to n lines from the start of path (decompressed and
+// transcoded from the named encoding when needed), used to detect the format
+// before a file is seeked.
+func HeadLines(path string, n int, encoding string) ([]string, error) {
+	te := fileEncoding(path, encoding)
+	rc, _, err := openInput(path)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	lr := newLineReader(te.reader(rc), 1<<20)
+	out := []string{}
+	for len(out) < n {
+		text, _, _, err := lr.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return out, err
+		}
+		out = append(out, te.decodeLine(text))
+	}
+	return out, nil
+}
@@ -14,25 +14,42 @@ type LogEntry struct {
 	Source     string         `json:"source,omitempty"`
 	FormatName string         `json:"formatName,omitempty"`
 	SchemaVer  string         `json:"schemaVersion,omitempty"`
+	// Offset and End locate the record in its source file so it can be paged
+	// back in after the ring evicts it; End is zero when not file-backed.
+	Offset int64 `json:"-"`
+	End    int64 `json:"-"`
+	// Marker is set on rows made from reader notices (rotation, truncation)
+	// rather than from log lines; Raw holds the notice.
+	Marker bool `json:"-"`
+	// Header is set by the delimited parser on the header row (at the start
+	// of the stream or repeated after a rotation): it names the columns and
+	// is not a record.
+	Header bool `json:"-"`
 }
 
 type FieldDef struct {
 	Name        string `json:"name"`
 	Type        string `json:"type"`
 	Description string `json:"description"`
-	PathOrGroup string `json:"pathOrGroup"`
+	PathOrGroup string `json:"pathOrGroup"` // json: path such as ".http.request.method"; regex/csv/logfmt: group or key name
 }
 
 type Schema struct {
 	FormatName      string            `json:"formatName"`
 	ProbableSources []string          `json:"probableSources"`
-	ParseStrategy   string            `json:"parseStrategy"` // json|regex|logfmt|kv|csv
+	ParseStrategy   string            `json:"parseStrategy"`      // json|regex|logfmt|kv|csv|mixed
+	Envelope        string            `json:"envelope,omitempty"` // cri: lines are wrapped in a container runtime prefix
 	TimeLayout      string            `json:"timeLayout"`
 	LevelMapping    map[string]string `json:"levelMapping"`
 	RegexPattern    string            `json:"regexPattern,omitempty"`
+	Delimiter       string            `json:"delimiter,omitempty"` // csv: field separator (default ",")
+	HeaderRow       bool              `json:"headerRow,omitempty"` // csv: the stream starts with a row naming the columns
 	Fields          []FieldDef        `json:"fields"`
 	Confidence      float64           `json:"confidence"`
 	SampleParsedRow map[string]any    `json:"sampleParsedRow"`
+	// Variants are the candidate schemas of a "mixed" stream; each line is
+	// parsed with the one that matches it best.
+	Variants []Schema `json:"variants,omitempty"`
 }
 
 func (s Schema) ColumnOrder() []string {
@@ -105,6 +122,61 @@ func (r *Ring) Snapshot() ([]LogEntry, uint64, uint64) {
 
 func (r *Ring) Cap() int { return r.cap }
 
+// Bounds returns the oldest and newest entries currently held.
+func (r *Ring) Bounds() (first, last LogEntry, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.size == 0 {
+		return LogEntry{}, LogEntry{}, false
+	}
+	return r.buf[r.start], r.buf[(r.start+r.size-1)%r.cap], true
+}
+
+// Prepend inserts entries paged in from disk before the oldest entry. When
+// the ring is full the newest entries make room; they are still on disk, so
+// this is not counted as dropped. It returns how many entries were evicted.
+func (r *Ring) Prepend(entries []LogEntry) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(entries) > r.cap {
+		entries = entries[len(entries)-r.cap:]
+	}
+	evicted := 0
+	if over := r.size + len(entries) - r.cap; over > 0 {
+		r.size -= over
+		evicted = over
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		r.start = (r.start - 1 + r.cap) % r.cap
+		r.buf[r.start] = entries[i]
+		r.size++
+	}
+	return evicted
+}
+
+// Append adds entries paged in from disk after the newest entry, evicting
+// the oldest ones without counting them as dropped. It returns how many
+// entries were evicted.
+func (r *Ring) Append(entries []LogEntry) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(entries) > r.cap {
+		entries = entries[:r.cap]
+	}
+	evicted := 0
+	for _, e := range entries {
+		if r.size < r.cap {
+			r.buf[(r.start+r.size)%r.cap] = e
+			r.size++
+			continue
+		}
+		r.buf[r.start] = e
+		r.start = (r.start + 1) % r.cap
+		evicted++
+	}
+	return evicted
+}
+
 func (r *Ring) Resize(newCap int) {
 	if newCap <= 0 {
 		return
@@ -0,0 +1,66 @@
+package parse
+
+import (
+	"strings"
+	"time"
+
+	"logsense/internal/model"
+)
+
+// CRILine is one line of the Kubernetes CRI container log format:
+// "<RFC3339Nano time> <stdout|stderr> <P|F> <payload>".
+type CRILine struct {
+	Time    string
+	Stream  string
+	Partial bool
+	Payload string
+}
+
+// SplitCRI splits a CRI log line into its envelope and payload.
+func SplitCRI(line string) (CRILine, bool) {
+	ts, rest, ok := strings.Cut(line, " ")
+	if !ok || len(ts) < len("2006-01-02T15:04:05Z") || ts[4] != '-' || ts[10] != 'T' {
+		return CRILine{}, false
+	}
+	stream, rest, ok := strings.Cut(rest, " ")
+	if !ok || (stream != "stdout" && stream != "stderr") {
+		return CRILine{}, false
+	}
+	tag, payload, _ := strings.Cut(rest, " ")
+	// The tag may carry further ':'-separated flags after P/F.
+	flag, _, _ := strings.Cut(tag, ":")
+	if flag != "P" && flag != "F" {
+		return CRILine{}, false
+	}
+	return CRILine{Time: ts, Stream: stream, Partial: flag == "P", Payload: payload}, true
+}
+
+// CRIParser unwraps the CRI envelope and hands the payload to the parser of
+// the inner format. The CRI timestamp is used when the payload has none.
+type CRIParser struct {
+	inner Parser
+}
+
+func (p *CRIParser) Parse(line, source string) model.LogEntry {
+	head, rest := splitRecord(line)
+	cl, ok := SplitCRI(head)
+	if !ok {
+		return p.inner.Parse(line, source)
+	}
+	payload := cl.Payload
+	if rest != "" {
+		payload += "\n" + rest
+	}
+	e := p.inner.Parse(payload, source)
+	e.Raw = line
+	e.Fields["stream"] = cl.Stream
+	if e.Timestamp == nil {
+		if t, err := time.Parse(time.RFC3339Nano, cl.Time); err == nil {
+			e.Timestamp = &t
+			if _, ok := e.Fields["ts"]; !ok {
+				e.Fields["ts"] = cl.Time
+			}
+		}
+	}
+	return e
+}
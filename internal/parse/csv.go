@@ -0,0 +1,102 @@
+package parse
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"logsense/internal/model"
+)
+
+// CSVParser parses delimited lines (CSV, TSV, ';' or '|' separated). Columns
+// are named after the schema fields in order, which detection takes from the
+// header row; columns past the known names are called col<N> (1-based).
+type CSVParser struct {
+	schema  model.Schema
+	layout  string
+	comma   rune
+	names   []string
+	timeCol int
+	lvlCol  int
+}
+
+func NewCSVParser(s model.Schema, forcedLayout string) (Parser, error) {
+	// Like an invalid regex, an unusable delimiter is not an error: fall
+	// back to a comma.
+	comma := ','
+	if r, size := utf8.DecodeRuneInString(s.Delimiter); size > 0 && size == len(s.Delimiter) && r != '"' && r != '\r' && r != '\n' && r != utf8.RuneError {
+		comma = r
+	}
+	p := &CSVParser{schema: s, layout: fallbackLayout(s.TimeLayout, forcedLayout), comma: comma, timeCol: -1, lvlCol: -1}
+	for i, f := range s.Fields {
+		p.names = append(p.names, f.Name)
+		switch n := strings.ToLower(f.Name); {
+		case p.timeCol < 0 && IsTimeColumn(n):
+			p.timeCol = i
+		case p.lvlCol < 0 && (n == "level" || n == "lvl" || n == "severity" || n == "loglevel" || n == "log_level"):
+			p.lvlCol = i
+		}
+	}
+	return p, nil
+}
+
+// IsTimeColumn reports whether a column name denotes a timestamp.
+func IsTimeColumn(name string) bool {
+	n := strings.ToLower(name)
+	switch n {
+	case "ts", "time", "timestamp", "@timestamp", "date", "datetime":
+		return true
+	}
+	return strings.HasSuffix(n, "_time") || strings.HasSuffix(n, "_ts") || strings.HasSuffix(n, "_at") || strings.HasSuffix(n, "timestamp")
+}
+
+func (p *CSVParser) Parse(line, source string) model.LogEntry {
+	e := model.LogEntry{Raw: line, Fields: map[string]any{}, Source: source, FormatName: p.schema.FormatName}
+	r := csv.NewReader(strings.NewReader(line))
+	r.Comma = p.comma
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = p.comma != '\t'
+	rec, err := r.Read()
+	if err != nil || len(rec) < 2 {
+		e.Fields["msg"] = line
+		return e
+	}
+	if p.schema.HeaderRow && p.isHeader(rec) {
+		e.Header = true
+		return e
+	}
+	for i, v := range rec {
+		name := fmt.Sprintf("col%d", i+1)
+		if i < len(p.names) {
+			name = p.names[i]
+		}
+		e.Fields[name] = typedValue(v)
+	}
+	if p.timeCol >= 0 && p.timeCol < len(rec) {
+		if t, err := parseTime(p.layout, rec[p.timeCol]); err == nil {
+			e.Timestamp = &t
+		}
+	}
+	if p.lvlCol >= 0 && p.lvlCol < len(rec) && rec[p.lvlCol] != "" {
+		e.Level = normalizeLevel(p.schema, rec[p.lvlCol])
+	}
+	return e
+}
+
+// isHeader reports whether rec is the header row the schema was detected
+// from: each cell is its column's name, blank (detection named the column
+// by content) or a duplicate that detection suffixed with its position.
+func (p *CSVParser) isHeader(rec []string) bool {
+	if len(rec) != len(p.names) {
+		return false
+	}
+	for i, c := range rec {
+		c = strings.TrimSpace(c)
+		if c != "" && c != p.names[i] && p.names[i] != fmt.Sprintf("%s_%d", c, i+1) {
+			return false
+		}
+	}
+	return true
+}
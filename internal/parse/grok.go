@@ -0,0 +1,337 @@
+package parse
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Grok support: regex schemas may reference named patterns with
+// %{PATTERN}, %{PATTERN:field} or %{PATTERN:field:int|float}. Patterns come
+// from the bundled library (grok-patterns, the common Logstash set) and from
+// the user files of a GrokLibrary passed in Options, and are expanded into a
+// plain RE2 expression before the regex is compiled.
+
+//go:embed grok-patterns
+var bundledGrokPatterns string
+
+// maxGrokDepth bounds pattern nesting, which also stops reference cycles.
+const maxGrokDepth = 32
+
+var reGrokRef = regexp.MustCompile(`%\{(\w+)(?::([^:}]+))?(?::(\w+))?\}`)
+
+// grokTimeLayouts are the Go layouts for timestamps captured with one of
+// these patterns, used when the schema does not set a time layout.
+var grokTimeLayouts = map[string]string{
+	"HTTPDATE":          "02/Jan/2006:15:04:05 -0700",
+	"SYSLOGTIMESTAMP":   time.Stamp,
+	"TIMESTAMP_ISO8601": time.RFC3339Nano,
+	"DATESTAMP_RFC2822": "Mon, 2 Jan 2006 15:04:05 -0700",
+}
+
+var bundledGrok struct {
+	once     sync.Once
+	patterns map[string]string
+}
+
+// bundledGrokLibrary returns the parsed bundled patterns. The map is shared
+// and must not be modified.
+func bundledGrokLibrary() map[string]string {
+	bundledGrok.once.Do(func() {
+		bundledGrok.patterns = map[string]string{}
+		// The bundled file is known to be well formed
+		_ = readGrokPatterns(strings.NewReader(bundledGrokPatterns), bundledGrok.patterns)
+	})
+	return bundledGrok.patterns
+}
+
+// GrokLibrary is a set of named grok patterns: the bundled ones plus those
+// of user files. A nil library holds the bundled patterns only. It is not
+// modified once built, so parsers may share it.
+type GrokLibrary struct {
+	patterns map[string]string
+}
+
+// LoadGrokLibrary builds a library from the bundled patterns and the
+// patterns of each path, a file or a directory whose files are all read.
+// Files hold one "NAME pattern" per line; blank lines and lines starting
+// with '#' are ignored. Later definitions override earlier ones, including
+// bundled patterns.
+func LoadGrokLibrary(paths ...string) (*GrokLibrary, error) {
+	lib := &GrokLibrary{patterns: map[string]string{}}
+	for name, p := range bundledGrokLibrary() {
+		lib.patterns[name] = p
+	}
+	for _, path := range paths {
+		files := []string{path}
+		if st, err := os.Stat(path); err != nil {
+			return nil, err
+		} else if st.IsDir() {
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			files = files[:0]
+			for _, e := range entries {
+				if !e.IsDir() {
+					files = append(files, filepath.Join(path, e.Name()))
+				}
+			}
+		}
+		for _, file := range files {
+			f, err := os.Open(file)
+			if err != nil {
+				return nil, err
+			}
+			err = readGrokPatterns(f, lib.patterns)
+			f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+		}
+	}
+	return lib, nil
+}
+
+func (l *GrokLibrary) lookup(name string) (string, bool) {
+	if l == nil {
+		p, ok := bundledGrokLibrary()[name]
+		return p, ok
+	}
+	p, ok := l.patterns[name]
+	return p, ok
+}
+
+func readGrokPatterns(r io.Reader, into map[string]string) error {
+	sc := bufio.NewScanner(r)
+	n := 0
+	for sc.Scan() {
+		n++
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, pat, ok := strings.Cut(line, " ")
+		if !ok || !isGrokName(name) {
+			return fmt.Errorf("line %d: want \"NAME pattern\"", n)
+		}
+		into[name] = strings.TrimSpace(pat)
+	}
+	return sc.Err()
+}
+
+func isGrokName(s string) bool {
+	for _, c := range s {
+		if !(c == '_' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// grokExpansion is a grok expression expanded to RE2, with the conversions
+// and time layouts implied by its field references.
+type grokExpansion struct {
+	lib     *GrokLibrary
+	pattern string
+	types   map[string]string // field -> int|float
+	layouts map[string]string // field -> Go time layout
+}
+
+// expandGrok expands the %{...} references of p with the patterns of lib.
+// Patterns without references are returned unchanged.
+func expandGrok(p string, lib *GrokLibrary) (grokExpansion, error) {
+	g := grokExpansion{lib: lib, types: map[string]string{}, layouts: map[string]string{}}
+	if !strings.Contains(p, "%{") {
+		g.pattern = p
+		return g, nil
+	}
+	out, err := g.expand(p, 0)
+	if err != nil {
+		return g, err
+	}
+	g.pattern = toRE2(out)
+	return g, nil
+}
+
+func (g *grokExpansion) expand(p string, depth int) (string, error) {
+	if depth > maxGrokDepth {
+		return "", fmt.Errorf("grok patterns nested too deep (cycle?)")
+	}
+	var firstErr error
+	out := reGrokRef.ReplaceAllStringFunc(p, func(ref string) string {
+		m := reGrokRef.FindStringSubmatch(ref)
+		name, field, typ := m[1], m[2], m[3]
+		body, ok := g.lib.lookup(name)
+		if !ok {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unknown grok pattern %%{%s}", name)
+			}
+			return ref
+		}
+		sub, err := g.expand(body, depth+1)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			return ref
+		}
+		if field == "" {
+			return "(?:" + sub + ")"
+		}
+		field = grokFieldName(field)
+		switch typ {
+		case "int", "float":
+			g.types[field] = typ
+		case "", "string":
+		default:
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unknown grok type %q in %s", typ, ref)
+			}
+		}
+		if layout, ok := grokTimeLayouts[name]; ok {
+			g.layouts[field] = layout
+		}
+		return "(?P<" + field + ">" + sub + ")"
+	})
+	return out, firstErr
+}
+
+// grokFieldName turns a Logstash field reference ("client.ip",
+// "[client][ip]") into an RE2 group name ("client_ip").
+func grokFieldName(f string) string {
+	f = strings.Trim(strings.ReplaceAll(f, "][", "_"), "[]")
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= '0' && r <= '9' || r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' {
+			return r
+		}
+		return '_'
+	}, f)
+}
+
+// toRE2 rewrites the Oniguruma constructs found in Logstash patterns that RE2
+// lacks: (?<name>...) becomes (?P<name>...), atomic groups (?>...) become
+// plain groups, possessive quantifiers lose their '+', and lookarounds are
+// dropped (they only guard boundaries in the usual patterns).
+func toRE2(p string) string {
+	var b strings.Builder
+	b.Grow(len(p))
+	quant := false // the last byte written was a quantifier
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch {
+		case c == '\\' && i+1 < len(p):
+			b.WriteString(p[i : i+2])
+			i++
+			quant = false
+			continue
+		case c == '[':
+			j := classEnd(p, i)
+			b.WriteString(p[i:j])
+			i = j - 1
+			quant = false
+			continue
+		case strings.HasPrefix(p[i:], "(?=") || strings.HasPrefix(p[i:], "(?!") || strings.HasPrefix(p[i:], "(?<=") || strings.HasPrefix(p[i:], "(?<!"):
+			i = groupEnd(p, i) - 1
+			continue
+		case strings.HasPrefix(p[i:], "(?>"):
+			b.WriteString("(?:")
+			i += 2
+			quant = false
+			continue
+		case strings.HasPrefix(p[i:], "(?<"):
+			b.WriteString("(?P<")
+			i += 2
+			quant = false
+			continue
+		case c == '+' && quant:
+			// Possessive quantifier
+			quant = false
+			continue
+		}
+		b.WriteByte(c)
+		quant = c == '*' || c == '+' || c == '}' || (c == '?' && i > 0 && p[i-1] != '(')
+	}
+	return b.String()
+}
+
+// classEnd returns the index just past the character class starting at i.
+func classEnd(p string, i int) int {
+	j := i + 1
+	if j < len(p) && p[j] == '^' {
+		j++
+	}
+	if j < len(p) && p[j] == ']' {
+		j++
+	}
+	for j < len(p) {
+		switch {
+		case p[j] == '\\':
+			j += 2
+			continue
+		case strings.HasPrefix(p[j:], "[:"):
+			if k := strings.Index(p[j+2:], ":]"); k >= 0 {
+				j += k + 4
+				continue
+			}
+		case p[j] == ']':
+			return j + 1
+		}
+		j++
+	}
+	return len(p)
+}
+
+// groupEnd returns the index just past the group opened at i.
+func groupEnd(p string, i int) int {
+	depth := 0
+	for j := i; j < len(p); j++ {
+		switch p[j] {
+		case '\\':
+			j++
+		case '[':
+			j = classEnd(p, j) - 1
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return j + 1
+			}
+		}
+	}
+	return len(p)
+}
+
+// convertGrokValue applies a grok field type (int or float) to a captured
+// value.
+func convertGrokValue(s, typ string) (any, bool) {
+	switch typ {
+	case "int":
+		n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		return n, err == nil
+	case "float":
+		f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		return f, err == nil
+	}
+	return nil, false
+}
+
+// ValidateRegexPattern checks that a regex schema pattern (plain RE2 or
+// grok with the patterns of lib) compiles.
+func ValidateRegexPattern(p string, lib *GrokLibrary) error {
+	g, err := expandGrok(sanitizeRegexPattern(p), lib)
+	if err != nil {
+		return err
+	}
+	_, err = regexp.Compile(g.pattern)
+	return err
+}
@@ -0,0 +1,230 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"logsense/internal/model"
+)
+
+// JSONOptions controls how nested JSON values are turned into columns.
+type JSONOptions struct {
+	// Depth is how many levels of nested objects are flattened into dotted
+	// keys ("http.request.method"); deeper objects are kept as one value.
+	// 0 keeps every nested object as one value.
+	Depth int
+	// IndexArrays flattens array elements into "key.0", "key.1", ... (each
+	// element counts as a level); otherwise arrays are kept as one value.
+	IndexArrays bool
+}
+
+// maxIndexedArray is the longest array flattened with IndexArrays; longer
+// ones are kept whole so a big list does not turn into hundreds of columns.
+const maxIndexedArray = 16
+
+// defaultJSONDepth is the flattening depth used when Options.JSON is nil.
+const defaultJSONDepth = 3
+
+// flattenJSON copies m into dst, prefixing keys with prefix and expanding
+// nested objects (and arrays, with IndexArrays) up to depth levels.
+func flattenJSON(dst map[string]any, prefix string, m map[string]any, depth int, indexArrays bool) {
+	for k, v := range m {
+		flattenValue(dst, prefix+k, v, depth, indexArrays)
+	}
+}
+
+func flattenValue(dst map[string]any, key string, v any, depth int, indexArrays bool) {
+	switch t := v.(type) {
+	case map[string]any:
+		if depth > 0 && len(t) > 0 {
+			flattenJSON(dst, key+".", t, depth-1, indexArrays)
+			return
+		}
+	case []any:
+		if indexArrays && depth > 0 && len(t) > 0 && len(t) <= maxIndexedArray {
+			for i, e := range t {
+				flattenValue(dst, key+"."+strconv.Itoa(i), e, depth-1, indexArrays)
+			}
+			return
+		}
+	}
+	dst[key] = v
+}
+
+type stepKind int
+
+const (
+	stepKey stepKind = iota
+	stepIndex
+	stepAll
+)
+
+// pathStep is one step of a field path: an object key, an array index
+// (negative counts from the end) or a wildcard over all elements.
+type pathStep struct {
+	kind  stepKind
+	key   string
+	index int
+}
+
+// fieldPath extracts the value at steps into the column name.
+type fieldPath struct {
+	name  string
+	steps []pathStep
+}
+
+// compilePath parses a field path in the jq/JSONPath subset used by
+// FieldDef.PathOrGroup: ".http.request.method", "$.items[0].name",
+// `.labels["app.kubernetes.io/name"]`, "spans[*].id". The leading "." or
+// "$" is optional.
+func compilePath(p string) ([]pathStep, error) {
+	s := strings.TrimSpace(p)
+	s = strings.TrimPrefix(s, "$")
+	var steps []pathStep
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '.':
+			i++
+			if i < len(s) && s[i] == '[' {
+				continue
+			}
+			key, n := readPathKey(s[i:])
+			if key == "" {
+				return nil, fmt.Errorf("empty key at offset %d in path %q", i, p)
+			}
+			steps = append(steps, pathStep{kind: stepKey, key: key})
+			i += n
+		case '[':
+			st, n, err := readPathBracket(s[i:])
+			if err != nil {
+				return nil, fmt.Errorf("path %q: %w", p, err)
+			}
+			steps = append(steps, st)
+			i += n
+		default:
+			if i > 0 {
+				return nil, fmt.Errorf("unexpected %q at offset %d in path %q", s[i], i, p)
+			}
+			key, n := readPathKey(s)
+			steps = append(steps, pathStep{kind: stepKey, key: key})
+			i += n
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("empty path %q", p)
+	}
+	return steps, nil
+}
+
+// readPathKey reads a bare key up to the next "." or "[".
+func readPathKey(s string) (string, int) {
+	n := strings.IndexAny(s, ".[")
+	if n < 0 {
+		n = len(s)
+	}
+	return s[:n], n
+}
+
+// readPathBracket reads a ["key"], ['key'], [N] or [*] step starting at s[0] == '['.
+func readPathBracket(s string) (pathStep, int, error) {
+	if len(s) > 1 && (s[1] == '"' || s[1] == '\'') {
+		q := s[1]
+		var b strings.Builder
+		for i := 2; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '\\' && i+1 < len(s):
+				i++
+				b.WriteByte(s[i])
+			case c == q:
+				if i+1 >= len(s) || s[i+1] != ']' {
+					return pathStep{}, 0, errors.New("quoted key must be followed by ]")
+				}
+				return pathStep{kind: stepKey, key: b.String()}, i + 2, nil
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return pathStep{}, 0, errors.New("unterminated quoted key")
+	}
+	end := strings.IndexByte(s, ']')
+	if end < 0 {
+		return pathStep{}, 0, errors.New("missing ]")
+	}
+	inner := strings.TrimSpace(s[1:end])
+	if inner == "*" {
+		return pathStep{kind: stepAll}, end + 1, nil
+	}
+	idx, err := strconv.Atoi(inner)
+	if err != nil {
+		return pathStep{}, 0, fmt.Errorf("invalid index %q", inner)
+	}
+	return pathStep{kind: stepIndex, index: idx}, end + 1, nil
+}
+
+// lookupPath returns the value at steps inside v. A wildcard step collects
+// the matches of the remaining steps in every element into a list.
+func lookupPath(v any, steps []pathStep) (any, bool) {
+	for i, st := range steps {
+		switch st.kind {
+		case stepKey:
+			m, ok := v.(map[string]any)
+			if !ok {
+				return nil, false
+			}
+			if v, ok = m[st.key]; !ok {
+				return nil, false
+			}
+		case stepIndex:
+			a, ok := v.([]any)
+			if !ok {
+				return nil, false
+			}
+			idx := st.index
+			if idx < 0 {
+				idx += len(a)
+			}
+			if idx < 0 || idx >= len(a) {
+				return nil, false
+			}
+			v = a[idx]
+		case stepAll:
+			a, ok := v.([]any)
+			if !ok {
+				return nil, false
+			}
+			out := []any{}
+			for _, e := range a {
+				if r, ok := lookupPath(e, steps[i+1:]); ok {
+					out = append(out, r)
+				}
+			}
+			if len(out) == 0 {
+				return nil, false
+			}
+			return out, true
+		}
+	}
+	return v, true
+}
+
+// jsonFieldPaths compiles the schema fields whose PathOrGroup is more than
+// their own top-level key. Invalid paths are skipped.
+func jsonFieldPaths(fields []model.FieldDef) []fieldPath {
+	var out []fieldPath
+	for _, f := range fields {
+		if f.PathOrGroup == "" || f.Name == "" {
+			continue
+		}
+		steps, err := compilePath(f.PathOrGroup)
+		if err != nil {
+			continue
+		}
+		if len(steps) == 1 && steps[0].kind == stepKey && steps[0].key == f.Name {
+			continue
+		}
+		out = append(out, fieldPath{name: f.Name, steps: steps})
+	}
+	return out
+}
@@ -0,0 +1,138 @@
+package parse
+
+import "strings"
+
+// logfmtPair is one key/value of a logfmt line. Bare keys (no '=') are
+// flags and have the value true; other values are typed by typedValue.
+type logfmtPair struct {
+	key   string
+	value any
+	flag  bool
+}
+
+// tokenizeLogfmt splits a logfmt line into its pairs, in order and with
+// repeated keys kept. Values may be double-quoted, with \" \\ \n \r \t
+// escapes; a quote inside an unquoted value is literal. Stray '=' and quotes
+// where a key is expected are skipped. Words are only taken as flags when
+// most tokens are key=value pairs: in prose or an access log line with a
+// stray '=' they are just words, and a line without any pair is not logfmt.
+func tokenizeLogfmt(s string) []logfmtPair {
+	var pairs []logfmtPair
+	valued := 0
+	i := 0
+	for i < len(s) {
+		if s[i] <= ' ' {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && s[i] > ' ' && s[i] != '=' && s[i] != '"' {
+			i++
+		}
+		key := s[start:i]
+		if key == "" {
+			// Garbage where a key should start: skip to the next space
+			for i < len(s) && s[i] > ' ' {
+				i++
+			}
+			continue
+		}
+		if i >= len(s) || s[i] != '=' {
+			if i < len(s) && s[i] == '"' {
+				// key"... is not a pair; drop the rest of the token
+				for i < len(s) && s[i] > ' ' {
+					i++
+				}
+				continue
+			}
+			pairs = append(pairs, logfmtPair{key: key, value: true, flag: true})
+			continue
+		}
+		i++ // '='
+		valued++
+		if i < len(s) && s[i] == '"' {
+			var v string
+			v, i = unquoteLogfmt(s, i+1)
+			pairs = append(pairs, logfmtPair{key: key, value: v})
+			continue
+		}
+		start = i
+		for i < len(s) && s[i] > ' ' {
+			i++
+		}
+		pairs = append(pairs, logfmtPair{key: key, value: typedValue(s[start:i])})
+	}
+	if valued == 0 {
+		return nil
+	}
+	if valued*2 <= len(pairs) {
+		kv := pairs[:0]
+		for _, p := range pairs {
+			if !p.flag {
+				kv = append(kv, p)
+			}
+		}
+		pairs = kv
+	}
+	return pairs
+}
+
+// unquoteLogfmt reads a quoted value starting just after its opening quote
+// at i and returns it with escapes resolved, plus the index after the
+// closing quote. An unterminated value runs to the end of s.
+func unquoteLogfmt(s string, i int) (string, int) {
+	start := i
+	for i < len(s) && s[i] != '"' && s[i] != '\\' {
+		i++
+	}
+	if i >= len(s) || s[i] == '"' {
+		// No escapes: no copy needed
+		return s[start:i], min(i+1, len(s))
+	}
+	var b strings.Builder
+	b.WriteString(s[start:i])
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == '"':
+			return b.String(), i + 1
+		case c == '\\' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '"', '\\':
+				b.WriteByte(s[i])
+			default:
+				// Unknown escape: keep it as written
+				b.WriteByte('\\')
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+		i++
+	}
+	return b.String(), i
+}
+
+// logfmtFields turns pairs into entry fields. A key seen more than once
+// keeps all its values, in order, as a []any.
+func logfmtFields(pairs []logfmtPair) map[string]any {
+	fields := make(map[string]any, len(pairs))
+	for _, p := range pairs {
+		prev, seen := fields[p.key]
+		if !seen {
+			fields[p.key] = p.value
+		} else if list, ok := prev.([]any); ok {
+			fields[p.key] = append(list, p.value)
+		} else {
+			fields[p.key] = []any{prev, p.value}
+		}
+	}
+	return fields
+}
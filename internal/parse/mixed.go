@@ -0,0 +1,66 @@
+package parse
+
+import (
+	"sync"
+
+	"logsense/internal/model"
+)
+
+// MixedParser parses heterogeneous streams: every line is handed to the
+// parser of each schema variant and the entry that extracted the most is
+// kept. On a tie the variant that last won for the line's source is
+// preferred, then the variant listed first.
+type MixedParser struct {
+	schema   model.Schema
+	parsers  []Parser
+	mu       sync.Mutex
+	bySource map[string]int
+}
+
+func NewMixedParser(s model.Schema, forcedLayout string, opt Options) (Parser, error) {
+	p := &MixedParser{schema: s, bySource: map[string]int{}}
+	for _, v := range s.Variants {
+		vp, err := NewParser(v, forcedLayout, opt)
+		if err != nil {
+			return nil, err
+		}
+		p.parsers = append(p.parsers, vp)
+	}
+	return p, nil
+}
+
+func (p *MixedParser) Parse(line, source string) model.LogEntry {
+	p.mu.Lock()
+	last, seen := p.bySource[source]
+	p.mu.Unlock()
+	best, bestScore, bestIdx := model.LogEntry{}, -1, -1
+	for i, vp := range p.parsers {
+		e := vp.Parse(line, source)
+		sc := entryScore(e)
+		if sc > bestScore || (sc == bestScore && seen && i == last) {
+			best, bestScore, bestIdx = e, sc, i
+		}
+	}
+	if bestIdx < 0 {
+		return model.LogEntry{Raw: line, Fields: map[string]any{"msg": line}, Source: source, FormatName: p.schema.FormatName}
+	}
+	if !seen || last != bestIdx {
+		p.mu.Lock()
+		p.bySource[source] = bestIdx
+		p.mu.Unlock()
+	}
+	return best
+}
+
+// entryScore rates how well a parser understood a line: the number of fields
+// it extracted, plus a bonus for a timestamp and a level.
+func entryScore(e model.LogEntry) int {
+	n := len(e.Fields)
+	if e.Timestamp != nil {
+		n += 2
+	}
+	if e.Level != "" {
+		n++
+	}
+	return n
+}
@@ -14,15 +14,41 @@ type Parser interface {
 	Parse(line, source string) model.LogEntry
 }
 
-func NewParser(s model.Schema, forcedLayout string) (Parser, error) {
+// Options are the parser settings that come from the command line rather
+// than from the schema. The zero value uses the defaults.
+type Options struct {
+	// Grok holds the patterns regex schemas may reference; nil means the
+	// bundled ones.
+	Grok *GrokLibrary
+	// JSON controls how nested JSON is flattened; nil flattens three levels
+	// and keeps arrays whole.
+	JSON *JSONOptions
+}
+
+func NewParser(s model.Schema, forcedLayout string, opt Options) (Parser, error) {
+	if s.Envelope == "cri" {
+		innerSchema := s
+		innerSchema.Envelope = ""
+		inner, err := NewParser(innerSchema, forcedLayout, opt)
+		if err != nil {
+			return nil, err
+		}
+		return &CRIParser{inner: inner}, nil
+	}
+	if s.ParseStrategy == "mixed" && len(s.Variants) > 0 {
+		return NewMixedParser(s, forcedLayout, opt)
+	}
 	if s.ParseStrategy == "json" {
-		return &JSONParser{schema: s, layout: fallbackLayout(s.TimeLayout, forcedLayout)}, nil
+		return NewJSONParser(s, forcedLayout, opt), nil
+	}
+	if s.ParseStrategy == "csv" {
+		return NewCSVParser(s, forcedLayout)
 	}
 	if s.ParseStrategy == "logfmt" || s.ParseStrategy == "kv" {
 		return &LogfmtParser{schema: s, layout: fallbackLayout(s.TimeLayout, forcedLayout)}, nil
 	}
 	// default regex
-	return NewRegexParser(s, forcedLayout)
+	return NewRegexParser(s, forcedLayout, opt)
 }
 
 func fallbackLayout(a, forced string) string {
@@ -39,16 +65,35 @@ func fallbackLayout(a, forced string) string {
 type JSONParser struct {
 	schema model.Schema
 	layout string
+	opts   JSONOptions
+	paths  []fieldPath
+}
+
+// NewJSONParser builds a JSON lines parser that flattens nested objects
+// according to opt.JSON and extracts schema fields whose PathOrGroup is a
+// path expression (e.g. ".http.request.method").
+func NewJSONParser(s model.Schema, forcedLayout string, opt Options) *JSONParser {
+	jo := JSONOptions{Depth: defaultJSONDepth}
+	if opt.JSON != nil {
+		jo = *opt.JSON
+	}
+	return &JSONParser{schema: s, layout: fallbackLayout(s.TimeLayout, forcedLayout), opts: jo, paths: jsonFieldPaths(s.Fields)}
 }
 
 func (p *JSONParser) Parse(line, source string) model.LogEntry {
 	var m map[string]any
-	_ = json.Unmarshal([]byte(line), &m)
-	e := model.LogEntry{Raw: line, Fields: map[string]any{}, Source: source, FormatName: p.schema.FormatName}
-	// Copy fields
-	for k, v := range m {
-		e.Fields[k] = v
+	rest := ""
+	if err := json.Unmarshal([]byte(line), &m); err != nil && strings.Contains(line, "\n") {
+		// A JSON record followed by joined continuation lines (e.g. a stack trace)
+		var head string
+		head, rest = splitRecord(line)
+		m = nil
+		_ = json.Unmarshal([]byte(head), &m)
 	}
+	e := model.LogEntry{Raw: line, Fields: map[string]any{}, Source: source, FormatName: p.schema.FormatName}
+	// Copy fields, nested objects as dotted keys
+	flattenJSON(e.Fields, "", m, p.opts.Depth, p.opts.IndexArrays)
+	var inner map[string]any
 	// If there is a JSON-encoded payload inside a string field (e.g., k8s container logs with `log`),
 	// attempt to parse and merge its keys for better column discovery.
 	// Prefer common payload keys in order.
@@ -58,17 +103,14 @@ func (p *JSONParser) Parse(line, source string) model.LogEntry {
 			if s, ok := raw.(string); ok {
 				t := strings.TrimSpace(s)
 				if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
-					var inner map[string]any
 					if err := json.Unmarshal([]byte(t), &inner); err == nil {
 						// Merge inner fields into entry fields (do not remove original wrapper field)
-						for ik, iv := range inner {
-							e.Fields[ik] = iv
-						}
+						flattenJSON(e.Fields, "", inner, p.opts.Depth, p.opts.IndexArrays)
 						// Best-effort timestamp/level from inner payload if not already set
 						if e.Timestamp == nil {
 							its := getStringPaths(inner, []string{"ts", "time", "timestamp"})
 							if its != "" {
-								if t, err := time.Parse(p.layout, its); err == nil {
+								if t, err := parseTime(p.layout, its); err == nil {
 									e.Timestamp = &t
 								}
 							}
@@ -81,21 +123,42 @@ func (p *JSONParser) Parse(line, source string) model.LogEntry {
 						}
 						break
 					}
+					inner = nil
 				}
 			}
 		}
 	}
-	// Best-effort timestamp and level
-	ts := getStringPaths(m, []string{"ts", "time", "timestamp"})
+	// Schema fields with a path pull nested values into their own column,
+	// from the record or else from its JSON payload
+	picked := map[string]any{}
+	for _, fp := range p.paths {
+		v, ok := lookupPath(m, fp.steps)
+		if !ok && inner != nil {
+			v, ok = lookupPath(inner, fp.steps)
+		}
+		if ok {
+			e.Fields[fp.name] = v
+			picked[fp.name] = v
+		}
+	}
+	// Best-effort timestamp and level; a field picked by path wins
+	ts := getStringPaths(picked, []string{"ts", "time", "timestamp"})
+	if ts == "" {
+		ts = getStringPaths(m, []string{"ts", "time", "timestamp"})
+	}
 	if ts != "" {
-		if t, err := time.Parse(p.layout, ts); err == nil {
+		if t, err := parseTime(p.layout, ts); err == nil {
 			e.Timestamp = &t
 		}
 	}
-	lvl := strings.ToUpper(getStringPaths(m, []string{"level", "lvl", "severity"}))
+	lvl := strings.ToUpper(getStringPaths(picked, []string{"level", "lvl", "severity"}))
+	if lvl == "" {
+		lvl = strings.ToUpper(getStringPaths(m, []string{"level", "lvl", "severity"}))
+	}
 	if lvl != "" {
 		e.Level = normalizeLevel(p.schema, lvl)
 	}
+	appendContinuation(&e, rest)
 	return e
 }
 
@@ -104,16 +167,34 @@ type RegexParser struct {
 	schema model.Schema
 	layout string
 	re     *regexp.Regexp
+	// types converts grok fields declared as %{PATTERN:field:int|float}
+	types map[string]string
 }
 
-func NewRegexParser(s model.Schema, forced string) (Parser, error) {
-	pat := sanitizeRegexPattern(s.RegexPattern)
-	re, err := regexp.Compile(pat)
+func NewRegexParser(s model.Schema, forced string, opt Options) (Parser, error) {
+	p := &RegexParser{schema: s, layout: fallbackLayout(s.TimeLayout, forced)}
+	g, err := expandGrok(sanitizeRegexPattern(s.RegexPattern), opt.Grok)
+	if err != nil {
+		// Like an invalid regex: the parser falls back to raw msg.
+		return p, nil
+	}
+	p.types = g.types
+	if s.TimeLayout == "" && forced == "" {
+		// Timestamps captured with a grok date pattern have a known layout
+		for _, name := range []string{"ts", "time", "timestamp"} {
+			if layout, ok := g.layouts[name]; ok {
+				p.layout = layout
+				break
+			}
+		}
+	}
+	re, err := regexp.Compile(g.pattern)
 	if err != nil {
 		// Leave regex nil so parser falls back to raw msg; caller may log.
-		return &RegexParser{schema: s, layout: fallbackLayout(s.TimeLayout, forced)}, nil
+		return p, nil
 	}
-	return &RegexParser{schema: s, layout: fallbackLayout(s.TimeLayout, forced), re: re}, nil
+	p.re = re
+	return p, nil
 }
 
 func (p *RegexParser) Parse(line, source string) model.LogEntry {
@@ -123,6 +204,13 @@ func (p *RegexParser) Parse(line, source string) model.LogEntry {
 		return e
 	}
 	m := p.re.FindStringSubmatch(line)
+	rest := ""
+	if m == nil && strings.Contains(line, "\n") {
+		// Multiline record: match the first line, keep the rest in msg
+		var head string
+		head, rest = splitRecord(line)
+		m = p.re.FindStringSubmatch(head)
+	}
 	if m == nil {
 		e.Fields["msg"] = line
 		return e
@@ -134,10 +222,14 @@ func (p *RegexParser) Parse(line, source string) model.LogEntry {
 			continue
 		}
 		val := m[i]
+		if _, dup := e.Fields[name]; dup && val == "" {
+			// The same name in another alternative that did match
+			continue
+		}
 		e.Fields[name] = val
 		captured++
 		if name == "ts" || name == "time" || name == "timestamp" {
-			if t, err := time.Parse(p.layout, val); err == nil {
+			if t, err := parseTime(p.layout, val); err == nil {
 				e.Timestamp = &t
 			}
 		}
@@ -149,6 +241,16 @@ func (p *RegexParser) Parse(line, source string) model.LogEntry {
 				e.Fields[name] = n
 			}
 		}
+		if name == "pri" && e.Level == "" {
+			e.Level = syslogSeverity(val)
+		}
+	}
+	for name, typ := range p.types {
+		if s, ok := e.Fields[name].(string); ok {
+			if v, ok := convertGrokValue(s, typ); ok {
+				e.Fields[name] = v
+			}
+		}
 	}
 	// Fallback: if regex has no named groups, map captures to schema field order
 	if captured == 0 {
@@ -159,7 +261,7 @@ func (p *RegexParser) Parse(line, source string) model.LogEntry {
 				val := m[i]
 				e.Fields[name] = val
 				if name == "ts" || name == "time" || name == "timestamp" {
-					if t, err := time.Parse(p.layout, val); err == nil {
+					if t, err := parseTime(p.layout, val); err == nil {
 						e.Timestamp = &t
 					}
 				}
@@ -174,10 +276,11 @@ func (p *RegexParser) Parse(line, source string) model.LogEntry {
 			}
 		}
 	}
+	appendContinuation(&e, rest)
 	return e
 }
 
-// logfmt parser (very basic, supports quoted values)
+// logfmt parser (see tokenizeLogfmt)
 type LogfmtParser struct {
 	schema model.Schema
 	layout string
@@ -185,61 +288,145 @@ type LogfmtParser struct {
 
 func (p *LogfmtParser) Parse(line, source string) model.LogEntry {
 	e := model.LogEntry{Raw: line, Fields: map[string]any{}, Source: source, FormatName: p.schema.FormatName}
-	parts := splitLogfmt(line)
-	for k, v := range parts {
-		e.Fields[k] = v
-	}
-	ts := pick(parts, "ts", "time", "timestamp")
+	head, rest := splitRecord(line)
+	e.Fields = logfmtFields(tokenizeLogfmt(head))
+	ts := getStringPaths(e.Fields, []string{"ts", "time", "timestamp"})
 	if ts != "" {
-		if t, err := time.Parse(p.layout, ts); err == nil {
+		if t, err := parseTime(p.layout, ts); err == nil {
 			e.Timestamp = &t
 		}
 	}
-	lvl := strings.ToUpper(pick(parts, "level", "lvl", "severity"))
+	lvl := strings.ToUpper(getStringPaths(e.Fields, []string{"level", "lvl", "severity"}))
 	if lvl != "" {
 		e.Level = normalizeLevel(p.schema, lvl)
 	}
+	appendContinuation(&e, rest)
 	return e
 }
 
-func pick(m map[string]string, keys ...string) string {
-	for _, k := range keys {
-		if v, ok := m[k]; ok {
-			return v
+// splitRecord separates the first physical line of a multiline record from
+// its continuation lines.
+func splitRecord(line string) (head, rest string) {
+	head, rest, _ = strings.Cut(line, "\n")
+	return head, rest
+}
+
+// appendContinuation adds the continuation lines of a multiline record to its
+// message field so stack traces stay attached to the entry that raised them.
+func appendContinuation(e *model.LogEntry, rest string) {
+	if rest == "" {
+		return
+	}
+	for _, k := range []string{"msg", "message"} {
+		if v, ok := e.Fields[k].(string); ok {
+			e.Fields[k] = v + "\n" + rest
+			return
 		}
 	}
-	return ""
+	e.Fields["msg"] = rest
 }
 
-func splitLogfmt(s string) map[string]string {
-	res := map[string]string{}
-	cur := ""
-	inQuote := false
-	key := ""
-	for i := 0; i < len(s); i++ {
-		c := s[i]
-		if c == '"' {
-			inQuote = !inQuote
-			continue
+// parseTime parses s with layout. Layouts without a year (e.g. the RFC3164
+// "Jan _2 15:04:05") yield year 0, so the current year is assumed instead,
+// stepping back one year for dates that would otherwise be in the future.
+func parseTime(layout, s string) (time.Time, error) {
+	t, err := time.Parse(layout, s)
+	if err != nil || t.Year() != 0 {
+		return t, err
+	}
+	now := time.Now()
+	t = t.AddDate(now.Year(), 0, 0)
+	if t.After(now.Add(24 * time.Hour)) {
+		t = t.AddDate(-1, 0, 0)
+	}
+	return t, nil
+}
+
+// syslogSeverity maps a syslog PRI value to a normalized level.
+func syslogSeverity(pri string) string {
+	n, err := strconv.Atoi(pri)
+	if err != nil || n < 0 {
+		return ""
+	}
+	switch n % 8 {
+	case 0, 1, 2:
+		return "FATAL"
+	case 3:
+		return "ERROR"
+	case 4:
+		return "WARN"
+	case 5, 6:
+		return "INFO"
+	default:
+		return "DEBUG"
+	}
+}
+
+// typedValue types an unquoted logfmt value or a CSV field: integers become
+// int64, decimals float64 and true/false bool. A number is only typed when
+// printing it gives s back, so zero-padded codes (007), decimals with
+// trailing zeros (1.10), -0, exponents and integers too large for int64
+// (numeric IDs) stay strings, as does anything else.
+func typedValue(s string) any {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	isInt, ok := numericLiteral(s)
+	if !ok {
+		return s
+	}
+	if isInt {
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil && strconv.FormatInt(n, 10) == s {
+			return n
 		}
-		if !inQuote && (c == ' ' || c == '\t') {
-			if key != "" {
-				res[key] = cur
-				key, cur = "", ""
-			}
-			continue
+		return s
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == s {
+		return f
+	}
+	return s
+}
+
+// numericLiteral reports whether s is a plain decimal number
+// ([-+]digits[.digits][e[-+]digits]) and whether it is an integer.
+func numericLiteral(s string) (isInt, ok bool) {
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		i++
+	}
+	digits := func() int {
+		n := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+			n++
 		}
-		if !inQuote && c == '=' {
-			key = cur
-			cur = ""
-			continue
+		return n
+	}
+	if digits() == 0 {
+		return false, false
+	}
+	isInt = true
+	if i < len(s) && s[i] == '.' {
+		i++
+		isInt = false
+		if digits() == 0 {
+			return false, false
 		}
-		cur += string(c)
 	}
-	if key != "" {
-		res[key] = cur
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		isInt = false
+		if i < len(s) && (s[i] == '-' || s[i] == '+') {
+			i++
+		}
+		if digits() == 0 {
+			return false, false
+		}
 	}
-	return res
+	return isInt, i == len(s)
 }
 
 func getStringPaths(m map[string]any, keys []string) string {
@@ -308,7 +495,7 @@ func replacePythonNamedGroup(s string) string {
 	var b strings.Builder
 	b.Grow(len(s))
 	for i := 0; i < len(s); i++ {
-		if s[i] == '(' && i+2 < len(s) && s[i+1] == '?' && s[i+2] == '<' {
+		if s[i] == '(' && i+3 < len(s) && s[i+1] == '?' && s[i+2] == '<' && s[i+3] != '=' && s[i+3] != '!' {
 			b.WriteString("(?P<")
 			i += 2
 			continue
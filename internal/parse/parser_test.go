@@ -1,13 +1,16 @@
 package parse
 
 import (
-	"logsense/internal/model"
+	"os"
+	"path/filepath"
 	"testing"
+
+	"logsense/internal/model"
 )
 
 func TestJSONParser(t *testing.T) {
 	s := model.Schema{FormatName: "json_lines", ParseStrategy: "json", TimeLayout: "2006-01-02T15:04:05Z07:00"}
-	p, _ := NewParser(s, "")
+	p, _ := NewParser(s, "", Options{})
 	e := p.Parse(`{"ts":"2025-01-01T12:00:00Z","level":"info","msg":"ok"}`, "stdin")
 	if e.Level != "INFO" {
 		t.Fatalf("level: %s", e.Level)
@@ -17,11 +20,210 @@ func TestJSONParser(t *testing.T) {
 	}
 }
 
+func TestJSONFlattenNested(t *testing.T) {
+	line := `{"msg":"ok","http":{"request":{"method":"GET","headers":{"host":"a"}},"status":200},"tags":["x","y"]}`
+	e := NewJSONParser(model.Schema{FormatName: "json_lines", ParseStrategy: "json"}, "", Options{JSON: &JSONOptions{Depth: 2}}).Parse(line, "")
+	if e.Fields["http.request.method"] != "GET" || e.Fields["http.status"] != float64(200) {
+		t.Fatalf("fields: %#v", e.Fields)
+	}
+	if _, ok := e.Fields["http.request.headers"].(map[string]any); !ok {
+		t.Fatalf("object past depth should stay whole: %#v", e.Fields)
+	}
+	if _, ok := e.Fields["http"]; ok {
+		t.Fatalf("flattened object kept: %#v", e.Fields)
+	}
+	if _, ok := e.Fields["tags"].([]any); !ok {
+		t.Fatalf("array should be kept: %#v", e.Fields)
+	}
+	e = NewJSONParser(model.Schema{FormatName: "json_lines", ParseStrategy: "json"}, "", Options{JSON: &JSONOptions{Depth: 1, IndexArrays: true}}).Parse(line, "")
+	if e.Fields["tags.0"] != "x" || e.Fields["tags.1"] != "y" {
+		t.Fatalf("indexed arrays: %#v", e.Fields)
+	}
+}
+
+func TestJSONFieldPaths(t *testing.T) {
+	s := model.Schema{FormatName: "ecs", ParseStrategy: "json", Fields: []model.FieldDef{
+		{Name: "ts", PathOrGroup: "$.event.created"},
+		{Name: "level", PathOrGroup: ".log.level"},
+		{Name: "app", PathOrGroup: `.labels["app.kubernetes.io/name"]`},
+		{Name: "first", PathOrGroup: ".spans[0].id"},
+		{Name: "last", PathOrGroup: ".spans[-1].id"},
+		{Name: "ids", PathOrGroup: "spans[*].id"},
+		{Name: "missing", PathOrGroup: ".nope.nope"},
+	}}
+	p, _ := NewParser(s, "", Options{})
+	e := p.Parse(`{"event":{"created":"2025-01-01T12:00:00Z"},"log":{"level":"warn"},"labels":{"app.kubernetes.io/name":"api"},"spans":[{"id":"a"},{"id":"b"}]}`, "")
+	if e.Timestamp == nil || e.Level != "WARN" {
+		t.Fatalf("ts/level from paths: %v %q", e.Timestamp, e.Level)
+	}
+	if e.Fields["app"] != "api" || e.Fields["first"] != "a" || e.Fields["last"] != "b" {
+		t.Fatalf("fields: %#v", e.Fields)
+	}
+	if ids, _ := e.Fields["ids"].([]any); len(ids) != 2 || ids[1] != "b" {
+		t.Fatalf("wildcard: %#v", e.Fields["ids"])
+	}
+	if _, ok := e.Fields["missing"]; ok {
+		t.Fatalf("missing path should not set a field")
+	}
+	for _, bad := range []string{"", ".", ".a..b", ".a[x]", `.a["b`} {
+		if _, err := compilePath(bad); err == nil {
+			t.Fatalf("path %q should not compile", bad)
+		}
+	}
+}
+
 func TestLogfmtParser(t *testing.T) {
 	s := model.Schema{FormatName: "logfmt", ParseStrategy: "logfmt", TimeLayout: "2006-01-02T15:04:05Z07:00"}
-	p, _ := NewParser(s, "")
+	p, _ := NewParser(s, "", Options{})
 	e := p.Parse(`time=2025-01-01T12:00:00Z level=warn msg="ok"`, "stdin")
 	if e.Level != "WARN" {
 		t.Fatalf("level: %s", e.Level)
 	}
 }
+
+func TestRegexParserSyslogPriority(t *testing.T) {
+	s := model.Schema{FormatName: "syslog_rfc3164", ParseStrategy: "regex", TimeLayout: "Jan _2 15:04:05",
+		RegexPattern: `^<(?P<pri>\d+)>(?P<ts>\w{3} [ \d]\d \d{2}:\d{2}:\d{2}) (?P<host>\S+) (?P<msg>.*)$`}
+	p, _ := NewParser(s, "", Options{})
+	e := p.Parse(`<34>Jan  1 12:00:00 host boom`, "udp")
+	if e.Level != "FATAL" {
+		t.Fatalf("level: %s", e.Level)
+	}
+	if e.Timestamp == nil || e.Timestamp.Year() == 0 {
+		t.Fatalf("timestamp: %v", e.Timestamp)
+	}
+}
+
+func TestCRIParserUnwrapsPayload(t *testing.T) {
+	s := model.Schema{FormatName: "cri/logfmt", ParseStrategy: "logfmt", Envelope: "cri", TimeLayout: "2006-01-02T15:04:05Z07:00"}
+	p, _ := NewParser(s, "", Options{})
+	e := p.Parse(`2025-01-01T12:00:00.5Z stderr F level=error msg="disk full"`, "pod")
+	if e.Level != "ERROR" || e.Fields["msg"] != "disk full" || e.Fields["stream"] != "stderr" {
+		t.Fatalf("unexpected entry: %+v", e)
+	}
+	if e.Timestamp == nil || e.Timestamp.Nanosecond() != 500000000 {
+		t.Fatalf("timestamp from CRI envelope: %v", e.Timestamp)
+	}
+}
+
+func TestMixedParserPicksFormatPerLine(t *testing.T) {
+	s := model.Schema{FormatName: "mixed", ParseStrategy: "mixed", Variants: []model.Schema{
+		{FormatName: "json_lines", ParseStrategy: "json", TimeLayout: "2006-01-02T15:04:05Z07:00"},
+		{FormatName: "logfmt", ParseStrategy: "logfmt", TimeLayout: "2006-01-02T15:04:05Z07:00"},
+		{FormatName: "apache_combined", ParseStrategy: "regex", TimeLayout: "02/Jan/2006:15:04:05 -0700",
+			RegexPattern: `^(?P<ip>\S+) \S+ \S+ \[(?P<ts>[^\]]+)\] "(?P<method>[A-Z]+) (?P<path>[^\s]+) [^"]+" (?P<status>\d{3})`},
+	}}
+	p, _ := NewParser(s, "", Options{})
+	cases := []struct{ line, format, field string }{
+		{`{"ts":"2025-01-01T12:00:00Z","level":"info","msg":"ok"}`, "json_lines", "msg"},
+		{`time=2025-01-01T12:00:01Z level=warn msg="slow request"`, "logfmt", "msg"},
+		{`127.0.0.1 - - [01/Jan/2025:12:00:02 +0000] "GET / HTTP/1.1" 200 1234 "-" "curl/8.0"`, "apache_combined", "status"},
+		// A query string is one '=' among many words, not a logfmt line full of flags
+		{`203.0.113.9 - - [01/Jan/2025:12:00:03 +0000] "GET /search?a=b HTTP/1.1" 200 512 "https://example.com/" "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0 Safari/537.36"`, "apache_combined", "status"},
+	}
+	for _, c := range cases {
+		e := p.Parse(c.line, "stdin")
+		if e.FormatName != c.format || e.Timestamp == nil {
+			t.Fatalf("%s: format %s, ts %v", c.line, e.FormatName, e.Timestamp)
+		}
+		if _, ok := e.Fields[c.field]; !ok {
+			t.Fatalf("%s: missing %s in %+v", c.line, c.field, e.Fields)
+		}
+	}
+}
+
+func TestLogfmtEscapesFlagsAndTypes(t *testing.T) {
+	s := model.Schema{FormatName: "logfmt", ParseStrategy: "logfmt", TimeLayout: "2006-01-02T15:04:05Z07:00"}
+	p, _ := NewParser(s, "", Options{})
+	e := p.Parse(`level=info msg="say \"hi\" to C:\\tmp" cached lat_ms=512 ratio=0.25 ok=false id=18446744073709551616 code="200" tag=a tag=b empty= zip=007 price=1.10 neg=-0 temp=-3.5`, "stdin")
+	want := map[string]any{
+		"msg":    `say "hi" to C:\tmp`,
+		"cached": true,
+		"lat_ms": int64(512),
+		"ratio":  0.25,
+		"ok":     false,
+		"id":     "18446744073709551616",
+		"code":   "200",
+		"empty":  "",
+		"zip":    "007",
+		"price":  "1.10",
+		"neg":    "-0",
+		"temp":   -3.5,
+	}
+	for k, v := range want {
+		if e.Fields[k] != v {
+			t.Fatalf("%s = %#v, want %#v", k, e.Fields[k], v)
+		}
+	}
+	if tags, ok := e.Fields["tag"].([]any); !ok || len(tags) != 2 || tags[0] != "a" || tags[1] != "b" {
+		t.Fatalf("tag = %#v", e.Fields["tag"])
+	}
+	if e.Level != "INFO" {
+		t.Fatalf("level: %s", e.Level)
+	}
+	// Plain text is not read as a list of flags
+	if e := p.Parse(`connection reset by peer`, "stdin"); len(e.Fields) != 0 {
+		t.Fatalf("plain text fields: %+v", e.Fields)
+	}
+}
+
+func TestCSVParserQuotedFields(t *testing.T) {
+	s := model.Schema{FormatName: "csv", ParseStrategy: "csv", Delimiter: ",", TimeLayout: "2006-01-02 15:04:05.999999999",
+		Fields: []model.FieldDef{{Name: "event_time"}, {Name: "user"}, {Name: "severity"}, {Name: "rows"}, {Name: "statement"}}}
+	p, _ := NewParser(s, "", Options{})
+	e := p.Parse(`2025-01-01 12:00:01.004,bob,warning,1,"UPDATE t SET a = 'x, y', ""b"" = 2",extra`, "audit")
+	if e.Timestamp == nil || e.Level != "WARN" {
+		t.Fatalf("ts %v level %q", e.Timestamp, e.Level)
+	}
+	if e.Fields["statement"] != `UPDATE t SET a = 'x, y', "b" = 2` || e.Fields["rows"] != int64(1) || e.Fields["col6"] != "extra" {
+		t.Fatalf("fields: %+v", e.Fields)
+	}
+	// A line that is not delimited is kept whole
+	if e := p.Parse(`panic: runtime error`, "audit"); e.Fields["msg"] != `panic: runtime error` {
+		t.Fatalf("fields: %+v", e.Fields)
+	}
+}
+
+func TestGrokBundledPatternsCompile(t *testing.T) {
+	for name := range bundledGrokLibrary() {
+		if err := ValidateRegexPattern("%{"+name+"}", nil); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+}
+
+func TestGrokRegexSchema(t *testing.T) {
+	s := model.Schema{FormatName: "grok", ParseStrategy: "regex",
+		RegexPattern: `%{IPORHOST:client} %{USER} %{USER:auth} \[%{HTTPDATE:ts}\] "%{WORD:method} %{URIPATHPARAM:path} HTTP/%{NUMBER}" %{NUMBER:status:int} %{NUMBER:bytes:int} %{QS:referrer} %{QS:[user][agent]}`}
+	p, _ := NewParser(s, "", Options{})
+	e := p.Parse(`10.0.0.7 - frank [10/Oct/2025:13:55:36 -0700] "GET /a/b?x=1 HTTP/1.1" 200 2326 "-" "curl/8.0"`, "stdin")
+	if e.Timestamp == nil || e.Timestamp.Year() != 2025 {
+		t.Fatalf("timestamp: %v", e.Timestamp)
+	}
+	if e.Fields["client"] != "10.0.0.7" || e.Fields["path"] != "/a/b?x=1" || e.Fields["bytes"] != int64(2326) || e.Fields["user_agent"] != `"curl/8.0"` {
+		t.Fatalf("fields: %+v", e.Fields)
+	}
+
+	// User patterns in Logstash (Oniguruma) syntax
+	dir := t.TempDir()
+	defs := "# team patterns\nREQID (?<![0-9a-f])(?>[0-9a-f]{8})(?![0-9a-f])\nAPPLINE \\[%{REQID:req_id}\\] (?<level>%{LOGLEVEL}) %{GREEDYDATA:msg}\n"
+	if err := os.WriteFile(filepath.Join(dir, "app"), []byte(defs), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	lib, err := LoadGrokLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, _ = NewParser(model.Schema{FormatName: "grok", ParseStrategy: "regex", RegexPattern: `%{APPLINE}`}, "", Options{Grok: lib})
+	e = p.Parse(`[deadbeef] WARN cache miss`, "stdin")
+	if e.Fields["req_id"] != "deadbeef" || e.Level != "WARN" || e.Fields["msg"] != "cache miss" {
+		t.Fatalf("fields: %+v level %q", e.Fields, e.Level)
+	}
+	if err := ValidateRegexPattern(`%{NOPE:x}`, lib); err == nil {
+		t.Fatalf("expected unknown pattern error")
+	}
+	// The user patterns only exist in the library they were loaded into
+	if err := ValidateRegexPattern(`%{APPLINE}`, nil); err == nil {
+		t.Fatalf("user pattern leaked into the bundled library")
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
+	"strings"
 
 	"logsense/internal/model"
 )
@@ -60,3 +61,13 @@ func anyToString(v any) string {
 		return string(b)
 	}
 }
+
+// oneLine folds multiline records (e.g. joined stack traces) into a single
+// table row.
+func oneLine(s string) string {
+	if !strings.ContainsAny(s, "\r\n") {
+		return s
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	return strings.ReplaceAll(s, "\n", " ↵ ")
+}
@@ -0,0 +1,251 @@
+package ui
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"logsense/internal/ingest"
+	"logsense/internal/model"
+	"logsense/internal/parse"
+	"logsense/internal/util/logx"
+)
+
+// pageBlockBytes is how much of the file is read per paging step.
+const pageBlockBytes = 1 << 20
+
+// pagedMsg carries entries read back from disk beyond either end of the ring.
+type pagedMsg struct {
+	entries []model.LogEntry
+	older   bool
+	err     error
+}
+
+// entryFromLine parses an ingested line and keeps its file position.
+func (m *Model) entryFromLine(l ingest.Line) model.LogEntry {
+	if l.Marker {
+		// The reader starts over at the top of a rotated or truncated
+		// file, so that is where a checkpoint resumes until a line arrives
+		m.consumed[l.Source] = 0
+		return m.markerEntry(l)
+	}
+	e := parseLine(m.parser, l)
+	if l.End > 0 {
+		m.consumed[l.Source] = l.End
+	}
+	if l.Truncated > 0 {
+		m.truncated++
+	}
+	return e
+}
+
+// truncatedField is set on entries whose line was longer than the line
+// limit to the number of bytes cut from it.
+const truncatedField = "_truncated_bytes"
+
+// parseLine parses a line read from the source, keeping where it is in its
+// file and how much of it was cut.
+func parseLine(p parse.Parser, l ingest.Line) model.LogEntry {
+	e := p.Parse(l.Text, l.Source)
+	e.Offset, e.End = l.Offset, l.End
+	if l.Truncated > 0 {
+		e.Fields[truncatedField] = l.Truncated
+	}
+	return e
+}
+
+// markerEntry turns a reader notice into a warning row. The notice goes in
+// the message column when there is one; time columns get the time of the
+// notice and the others are left blank, so that the row shows the notice in
+// its first blank visible cell (see placeNotice) whatever the schema.
+func (m *Model) markerEntry(l ingest.Line) model.LogEntry {
+	now := l.When
+	e := model.LogEntry{Raw: l.Text, Fields: map[string]any{}, Timestamp: &now, Level: "warn", Source: l.Source, Marker: true}
+	msgCol := "msg"
+	for _, c := range m.deriveColumns() {
+		e.Fields[c] = ""
+		switch {
+		case c == "message":
+			msgCol = c
+		case parse.IsTimeColumn(c):
+			e.Fields[c] = now.Format("2006-01-02 15:04:05")
+		}
+	}
+	e.Fields[msgCol] = l.Text
+	return e
+}
+
+// placeNotice puts the text of a marker row in the first blank cell of the
+// row unless a visible column already shows it.
+func placeNotice(cells []string, text string) {
+	for _, c := range cells {
+		if c == text {
+			return
+		}
+	}
+	for i, c := range cells {
+		if c == "" {
+			cells[i] = text
+			return
+		}
+	}
+}
+
+// saveCheckpoints records how far each --file was read so the next session
+// can resume from there with --since-last.
+func (m *Model) saveCheckpoints() {
+	if m.source != string(ingest.SourceFile) {
+		return
+	}
+	for _, path := range m.cfg.FilePaths {
+		off, ok := m.consumed[path]
+		if !ok {
+			continue
+		}
+		if err := ingest.SaveCheckpoint(path, off); err != nil {
+			logx.Warnf("checkpoint: %s: %v", path, err)
+		}
+	}
+}
+
+// pagingIndex returns the index of the file backing the ring when entries
+// can be paged in from disk: a single plain file read without follow (nor
+// replayed, as the replay still owns the newest end), once it has been read.
+// A block paged in earlier would be evicted or sent again by the load.
+func (m *Model) pagingIndex() *ingest.Index {
+	if !m.ingestDone || m.follow || m.replayed || m.parser == nil || m.source != string(ingest.SourceFile) {
+		return nil
+	}
+	return m.ingestState.Index()
+}
+
+// pageOlder reads the block that precedes the oldest entry in the ring.
+func (m *Model) pageOlder() tea.Cmd {
+	ix := m.pagingIndex()
+	first, _, ok := m.ring.Bounds()
+	if ix == nil || !ok || m.paging || first.End == 0 || first.Offset == 0 {
+		return nil
+	}
+	m.paging = true
+	m.lastMsg = "loading older entries..."
+	return m.readPage(ix.Path(), first.Offset, true)
+}
+
+// pageNewer reads the block that follows the newest entry in the ring.
+func (m *Model) pageNewer() tea.Cmd {
+	ix := m.pagingIndex()
+	_, last, ok := m.ring.Bounds()
+	if ix == nil || !ok || m.paging || last.End == 0 {
+		return nil
+	}
+	m.paging = true
+	return m.readPage(ix.Path(), last.End, false)
+}
+
+func (m *Model) readPage(path string, offset int64, older bool) tea.Cmd {
+	opt := ingest.Options{ScanBufSize: m.scanBufSize, Multiline: m.multiline, Encoding: m.cfg.Encoding}
+	parser := m.backgroundParser()
+	return func() tea.Msg {
+		var lines []ingest.Line
+		var err error
+		if older {
+			lines, err = ingest.ReadBlockBefore(m.ctx, path, offset, pageBlockBytes, opt)
+		} else {
+			lines, err = ingest.ReadBlockAfter(m.ctx, path, offset, pageBlockBytes, opt)
+		}
+		entries := make([]model.LogEntry, 0, len(lines))
+		for _, l := range lines {
+			if e := parseLine(parser, l); !e.Header {
+				entries = append(entries, e)
+			}
+		}
+		return pagedMsg{entries: entries, older: older, err: err}
+	}
+}
+
+// applyPage merges a paged block into the ring and keeps the cursor next to
+// the row it was on before the block was added.
+func (m *Model) applyPage(msg pagedMsg) {
+	m.paging = false
+	m.lastMsg = ""
+	if msg.err != nil {
+		logx.Warnf("history: paging failed: %v", msg.err)
+		m.lastMsg = fmt.Sprintf("paging failed: %v", msg.err)
+		return
+	}
+	if len(msg.entries) == 0 {
+		return
+	}
+	var anchor int64
+	if first, last, ok := m.ring.Bounds(); ok {
+		anchor = first.Offset
+		if !msg.older {
+			anchor = last.Offset
+		}
+	}
+	if msg.older {
+		m.ring.Prepend(msg.entries)
+	} else {
+		m.ring.Append(msg.entries)
+	}
+	for _, e := range msg.entries {
+		if m.updateDiscoveryFromEntry(e) {
+			m.columnsDirty = true
+		}
+	}
+	m.refreshFiltered()
+	for i, e := range m.filtered {
+		if e.Offset != anchor {
+			continue
+		}
+		if msg.older && i > 0 {
+			i--
+		} else if !msg.older && i+1 < len(m.filtered) {
+			i++
+		}
+		m.tbl.SetCursor(i)
+		m.ensureCursorVisible()
+		break
+	}
+	logx.Debugf("history: paged %d %s entries", len(msg.entries), map[bool]string{true: "older", false: "newer"}[msg.older])
+}
+
+// filePosition renders the absolute position of the selected entry in the
+// file, e.g. "pos:12345 (37%)", or "" when it is not file-backed.
+func (m *Model) filePosition() string {
+	ix := m.pagingIndex()
+	cur := m.tbl.Cursor()
+	if ix == nil || cur < 0 || cur >= len(m.filtered) || m.filtered[cur].End == 0 {
+		return ""
+	}
+	e := m.filtered[cur]
+	lines, done := ix.Lines()
+	pct := ""
+	if size := ix.Size(); size > 0 && e.Offset <= size {
+		pct = fmt.Sprintf(" (%d%%)", e.Offset*100/size)
+	}
+	if m.posCache.offset != e.Offset || m.posCache.line == 0 {
+		if n, ok := ix.LineAt(e.Offset); ok {
+			m.posCache.offset, m.posCache.line = e.Offset, n
+		} else {
+			return fmt.Sprintf("pos:%s%s", humanBytes(e.Offset), pct)
+		}
+	}
+	if done {
+		return fmt.Sprintf("pos:%d/%d%s", m.posCache.line, lines, pct)
+	}
+	return fmt.Sprintf("pos:%d%s", m.posCache.line, pct)
+}
+
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
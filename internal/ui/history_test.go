@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"logsense/internal/config"
+	"logsense/internal/ingest"
+)
+
+func TestPagingWaitsForIngest(t *testing.T) {
+	var b strings.Builder
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&b, "2024-01-01T00:00:%02dZ INFO line %d\n", i, i)
+	}
+	path := filepath.Join(t.TempDir(), "app.log")
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cfg := &config.Config{FilePaths: []string{path}, MaxBuffer: 5, MaxLineBytes: 64 * 1024}
+	m := initialModel(ctx, cfg)
+	m.startIngest(nil)
+	var all []ingest.Line
+	for l := range m.lines {
+		all = append(all, l)
+	}
+	if len(all) != 50 {
+		t.Fatalf("read %d lines, want 50", len(all))
+	}
+	sample := make([]string, 0, 10)
+	for _, l := range all[:10] {
+		sample = append(sample, l.Text)
+	}
+	m.schema = m.detectSchema(sample)
+	p, err := m.newParser(m.schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.parser = p
+	m.detected = true
+
+	// Hand the lines to the model in two batches, as the tick would see them
+	// while the file is still being read
+	feed := make(chan ingest.Line, len(all))
+	m.lines = feed
+	for _, l := range all[:30] {
+		feed <- l
+	}
+	m.Update(tickMsg{})
+	if first, _, ok := m.ring.Bounds(); !ok || first.Offset == 0 {
+		t.Fatalf("ring should hold a later window, got %+v", first)
+	}
+	if m.pageOlder() != nil || m.pageNewer() != nil {
+		t.Fatalf("paging allowed while lines are still arriving")
+	}
+	for _, l := range all[30:] {
+		feed <- l
+	}
+	close(feed)
+	m.Update(tickMsg{})
+	if !m.ingestDone {
+		t.Fatalf("ingest not marked done after lines closed")
+	}
+	if m.pageOlder() == nil {
+		t.Fatalf("paging refused after the file was read")
+	}
+}
+
+func TestRotationResetsConsumed(t *testing.T) {
+	m := initialModel(context.Background(), &config.Config{MaxBuffer: 5, MaxLineBytes: 64 * 1024})
+	m.consumed["app.log"] = 120
+	m.entryFromLine(ingest.Line{Text: "file rotated", Source: "app.log", Marker: true})
+	if off, ok := m.consumed["app.log"]; !ok || off != 0 {
+		t.Fatalf("consumed after rotation = %d, %v; want 0", off, ok)
+	}
+}
@@ -13,27 +13,30 @@ import (
 	"github.com/charmbracelet/lipgloss"
 
 	"logsense/internal/config"
+	"logsense/internal/ingest"
 	"logsense/internal/model"
 )
 
 func initialModel(ctx context.Context, cfg *config.Config) *Model {
 	m := &Model{
-		ctx:             ctx,
-		cfg:             cfg,
-		ring:            model.NewRing(cfg.MaxBuffer),
-		tab:             tabStream,
-		state:           stateRunning,
-		help:            help.New(),
-		styles:          NewStyles(cfg.Theme == config.ThemeDark),
-		keymap:          DefaultKeyMap(),
-		search:          textinput.New(),
-		spin:            spinner.New(),
-		follow:          cfg.Follow,
-		scanBufSize:     1024 * 1024,
-		rowsDirty:       true,
-		columnsDirty:    true,
-		tailStartOffset: -1,
+		ctx:          ctx,
+		cfg:          cfg,
+		ring:         model.NewRing(cfg.MaxBuffer),
+		tab:          tabStream,
+		state:        stateRunning,
+		help:         help.New(),
+		styles:       NewStyles(cfg.Theme == config.ThemeDark),
+		keymap:       DefaultKeyMap(),
+		search:       textinput.New(),
+		spin:         spinner.New(),
+		follow:       cfg.Follow,
+		scanBufSize:  cfg.LineLimit(),
+		rowsDirty:    true,
+		columnsDirty: true,
+		consumed:     map[string]int64{},
 	}
+	m.ingestState = ingest.NewState()
+	m.ingestState.SetSupervise(cfg.Follow)
 	m.spin.Spinner = spinner.Dot
 	m.search.Placeholder = "search... (text or /regex/)"
 	m.search.CharLimit = 256
@@ -58,12 +61,38 @@ func initialModel(ctx context.Context, cfg *config.Config) *Model {
 }
 
 func Run(ctx context.Context, cfg *config.Config) error {
+	if err := ingest.ValidateEncoding(cfg.Encoding); err != nil {
+		return err
+	}
 	m := initialModel(ctx, cfg)
+	if err := m.buildMultiline(); err != nil {
+		return err
+	}
+	if cfg.Record != "" {
+		rec, err := ingest.CreateRecorder(cfg.Record)
+		if err != nil {
+			return err
+		}
+		defer rec.Close()
+		m.recorder = rec
+	}
 	p := tea.NewProgram(m, tea.WithContext(ctx))
 	_, err := p.Run()
+	m.saveCheckpoints()
 	return err
 }
 
+// buildMultiline sets up the ingest multiline stage from the --multiline*
+// flags.
+func (m *Model) buildMultiline() error {
+	rules, err := ingest.ParseMultilineRules(m.cfg.Multiline)
+	if err != nil {
+		return err
+	}
+	m.multiline = ingest.MultilineOptions{Rules: rules, Start: m.cfg.MultilineStart, Timeout: m.cfg.MultilineTimeout, MaxLines: m.cfg.MultilineLines}
+	return nil
+}
+
 func (m *Model) Init() tea.Cmd {
 	return tea.Batch(setupPipeline(m), tea.Tick(200*time.Millisecond, func(time.Time) tea.Msg { return tickMsg{} }))
 }
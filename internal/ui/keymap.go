@@ -4,6 +4,7 @@ import tea "github.com/charmbracelet/bubbletea"
 
 type KeyMap struct {
 	Pause        tea.Key
+	Latest       tea.Key
 	Follow       tea.Key
 	Search       tea.Key
 	Export       tea.Key
@@ -11,6 +12,7 @@ type KeyMap struct {
 	Redetect     tea.Key
 	Top          tea.Key
 	Bottom       tea.Key
+	GotoTime     tea.Key
 	Help         tea.Key
 	Quit         tea.Key
 	InspectorTab tea.Key
@@ -30,6 +32,7 @@ type KeyMap struct {
 func DefaultKeyMap() KeyMap {
 	return KeyMap{
 		Pause:        tea.Key{Type: tea.KeyRunes, Runes: []rune{' '}},
+		Latest:       tea.Key{Type: tea.KeyRunes, Runes: []rune{'R'}},
 		Follow:       tea.Key{Type: tea.KeyRunes, Runes: []rune{'t'}},
 		Search:       tea.Key{Type: tea.KeyRunes, Runes: []rune{'/'}},
 		Export:       tea.Key{Type: tea.KeyRunes, Runes: []rune{'e'}},
@@ -37,6 +40,7 @@ func DefaultKeyMap() KeyMap {
 		Redetect:     tea.Key{Type: tea.KeyRunes, Runes: []rune{'d'}},
 		Top:          tea.Key{Type: tea.KeyRunes, Runes: []rune{'g'}},
 		Bottom:       tea.Key{Type: tea.KeyRunes, Runes: []rune{'G'}},
+		GotoTime:     tea.Key{Type: tea.KeyRunes, Runes: []rune{'T'}},
 		Help:         tea.Key{Type: tea.KeyRunes, Runes: []rune{'?'}},
 		Quit:         tea.Key{Type: tea.KeyRunes, Runes: []rune{'q'}},
 		InspectorTab: tea.Key{Type: tea.KeyEnter},
@@ -18,34 +18,7 @@ import (
 
 // IO and pipeline orchestration
 func setupPipeline(m *Model) tea.Cmd {
-	// Create ingest
-	src := ingest.SourceDemo
-	if m.cfg.UseStdin {
-		src = ingest.SourceStdin
-	}
-	if !m.cfg.UseStdin && m.cfg.FilePath != "" {
-		src = ingest.SourceFile
-	}
-	m.source = string(src)
-	block := int64(0)
-	// Use runtime follow state, not only initial config
-	if !m.follow && m.cfg.BlockSizeMB > 0 {
-		block = int64(m.cfg.BlockSizeMB) * 1024 * 1024
-	}
-	// If a previous ingest is running, cancel it before starting a new one
-	if m.ingestCancel != nil {
-		m.ingestCancel()
-		m.ingestCancel = nil
-	}
-	// Create a child context so we can stop this ingest later (e.g., toggling follow)
-	ingestCtx, cancel := context.WithCancel(m.ctx)
-	m.ingestCancel = cancel
-	startOffset := int64(-1)
-	if m.follow {
-		startOffset = m.tailStartOffset
-	}
-	m.lines, m.errs = ingest.Read(ingestCtx, ingest.Options{Source: src, Path: m.cfg.FilePath, Follow: m.follow, ScanBufSize: m.scanBufSize, BlockSizeBytes: block, StartOffset: startOffset})
-	logx.Infof("ingest: source=%s path=%s follow=%v blockBytes=%d startOffset=%d", m.source, m.cfg.FilePath, m.follow, block, startOffset)
+	m.startIngest(nil)
 	// Prepare detection: collect first N lines then pick parser
 	return func() tea.Msg {
 		// Buffer for detection: wait at least 1 second AND at least 1 line.
@@ -80,41 +53,17 @@ func setupPipeline(m *Model) tea.Cmd {
 		for i := 0; i < len(buffered) && i < maxSample; i++ {
 			sample = append(sample, buffered[i].Text)
 		}
-		// Heuristics
-		g := detect.Heuristics(sample)
-		m.schema = g.Schema
-		logx.Infof("detect: heuristics format=%s strategy=%s conf=%.2f", m.schema.FormatName, m.schema.ParseStrategy, g.Confidence)
-		if m.cfg.ForceFormat != "" {
-			switch m.cfg.ForceFormat {
-			case "json":
-				m.schema.ParseStrategy = "json"
-			case "logfmt":
-				m.schema.ParseStrategy = "logfmt"
-			case "apache":
-				m.schema = detect.Heuristics([]string{"127.0.0.1 - - [01/Jan/2025:12:00:02 +0000] \"GET / HTTP/1.1\" 200 1234 \"-\" \"curl/8.0\""}).Schema
-			case "syslog":
-				m.schema = detect.Heuristics([]string{"<34>1 2025-01-01T00:00:00Z h a - - - msg"}).Schema
-			}
-			logx.Infof("detect: forced format=%s -> strategy=%s", m.cfg.ForceFormat, m.schema.ParseStrategy)
-		}
-		// If online and a file path is provided, try schema cache before creating parser
-		if !m.cfg.NoCache && strings.TrimSpace(m.cfg.FilePath) != "" {
-			if cs, ok := detect.LoadSchemaFromCache(m.cfg.FilePath); ok {
-				m.schema = cs
-				logx.Infof("detect: cache hit for %s -> format=%s strategy=%s", m.cfg.FilePath, m.schema.FormatName, m.schema.ParseStrategy)
-			} else {
-				logx.Infof("detect: no cache for %s", m.cfg.FilePath)
-			}
-		} else if m.cfg.NoCache {
-			logx.Infof("detect: cache disabled via --no-cache")
-		}
-		p, _ := parse.NewParser(m.schema, m.cfg.TimeLayout)
+		m.schema = m.detectSchema(sample)
+		p, _ := m.newParser(m.schema)
 		m.parser = p
 		// Replay ALL buffered lines so none are lost and infer columns from parsed fields
 		fieldSet := map[string]struct{}{}
 		var sampleRow map[string]any
 		for _, bl := range buffered {
-			e := p.Parse(bl.Text, bl.Source)
+			e := m.entryFromLine(bl)
+			if e.Header || !m.inTimeRange(e) {
+				continue
+			}
 			if sampleRow == nil {
 				sampleRow = e.Fields
 			}
@@ -124,23 +73,24 @@ func setupPipeline(m *Model) tea.Cmd {
 				}
 				fieldSet[k] = struct{}{}
 			}
+			if m.multiSource() {
+				fieldSet["source"] = struct{}{}
+			}
 			m.ring.Push(e)
 			if m.updateDiscoveryFromEntry(e) {
 				m.columnsDirty = true
 			}
 			m.rowsDirty = true
 		}
-		if len(fieldSet) > 0 {
+		// Delimited logs keep their columns in file order (the parser names
+		// fields after them)
+		if len(fieldSet) > 0 && m.schema.ParseStrategy != "csv" {
 			keys := make([]string, 0, len(fieldSet))
 			for k := range fieldSet {
 				keys = append(keys, k)
 			}
 			sort.Strings(keys)
-			fdefs := make([]model.FieldDef, 0, len(keys))
-			for _, k := range keys {
-				fdefs = append(fdefs, model.FieldDef{Name: k, Type: "string", Description: "", PathOrGroup: k})
-			}
-			m.schema.Fields = fdefs
+			m.schema.Fields = fieldDefsFor(m.schema.Fields, keys)
 			if sampleRow != nil {
 				m.schema.SampleParsedRow = sampleRow
 			}
@@ -150,6 +100,196 @@ func setupPipeline(m *Model) tea.Cmd {
 	}
 }
 
+// newParser builds a parser for s with the time layout and parser options
+// given on the command line.
+func (m *Model) newParser(s model.Schema) (parse.Parser, error) {
+	jo := parse.JSONOptions{Depth: m.cfg.JSONDepth, IndexArrays: m.cfg.JSONArrays == "index"}
+	return parse.NewParser(s, m.cfg.TimeLayout, parse.Options{Grok: m.cfg.Grok, JSON: &jo})
+}
+
+// fieldDefsFor returns one field per parsed key, keeping the definition the
+// schema already has for a key (so its PathOrGroup, type and description
+// survive) and adding plain ones for the others.
+func fieldDefsFor(existing []model.FieldDef, keys []string) []model.FieldDef {
+	byName := make(map[string]model.FieldDef, len(existing))
+	for _, f := range existing {
+		byName[f.Name] = f
+	}
+	fdefs := make([]model.FieldDef, 0, len(keys))
+	for _, k := range keys {
+		if f, ok := byName[k]; ok {
+			fdefs = append(fdefs, f)
+			continue
+		}
+		fdefs = append(fdefs, model.FieldDef{Name: k, Type: "string", Description: "", PathOrGroup: k})
+	}
+	return fdefs
+}
+
+// startIngest (re)starts reading the configured source. startOffsets is nil
+// for the first start, which applies --since-last, --since/--until and
+// --replay; otherwise it continues files where the previous ingest stopped
+// (see resumeOffsets).
+func (m *Model) startIngest(startOffsets map[string]int64) {
+	// Create ingest
+	src := ingest.SourceDemo
+	if m.cfg.UseStdin {
+		src = ingest.SourceStdin
+	}
+	paths := m.cfg.FilePaths
+	if !m.cfg.UseStdin && len(m.cfg.FilePaths) > 0 {
+		src = ingest.SourceFile
+	}
+	if !m.cfg.UseStdin && m.cfg.RotatedBase != "" {
+		src = ingest.SourceRotated
+		paths = []string{m.cfg.RotatedBase}
+	}
+	if !m.cfg.UseStdin && m.cfg.Dir != "" {
+		src = ingest.SourceDir
+		paths = []string{m.cfg.Dir}
+	}
+	if !m.cfg.UseStdin && m.cfg.Command != "" {
+		src = ingest.SourceCommand
+	}
+	if !m.cfg.UseStdin && m.cfg.Load != "" {
+		src = ingest.SourceCapture
+		paths = []string{m.cfg.Load}
+	}
+	listen := ""
+	if !m.cfg.UseStdin && m.cfg.ListenSyslog != "" {
+		src = ingest.SourceSyslog
+		listen = m.cfg.ListenSyslog
+	}
+	if !m.cfg.UseStdin && m.cfg.ListenHTTP != "" {
+		src = ingest.SourceHTTP
+		listen = m.cfg.ListenHTTP
+	}
+	if !m.cfg.UseStdin && m.cfg.ListenOTLP != "" {
+		src = ingest.SourceOTLP
+		listen = m.cfg.ListenOTLP
+	}
+	m.source = string(src)
+	block := int64(0)
+	// Use runtime follow state, not only initial config
+	if !m.follow && m.cfg.BlockSizeMB > 0 {
+		block = int64(m.cfg.BlockSizeMB) * 1024 * 1024
+	}
+	// If a previous ingest is running, cancel it before starting a new one
+	if m.ingestCancel != nil {
+		m.ingestCancel()
+		m.ingestCancel = nil
+	}
+	// Create a child context so we can stop this ingest later (e.g., toggling follow)
+	ingestCtx, cancel := context.WithCancel(m.ctx)
+	m.ingestCancel = cancel
+	opt := ingest.Options{Source: src, Paths: paths, Follow: m.follow, ScanBufSize: m.scanBufSize, BlockSizeBytes: block, StartOffset: -1, StartOffsets: startOffsets, Multiline: m.multiline, Encoding: m.cfg.Encoding, Include: m.cfg.Include, Command: m.cfg.Command, Listen: listen, Record: m.recorder, State: m.ingestState}
+	if startOffsets == nil {
+		m.applyStartOptions(&opt)
+	}
+	m.lines, m.errs = ingest.Read(ingestCtx, opt)
+	m.ingestDone = false
+	logx.Infof("ingest: source=%s paths=%v follow=%v blockBytes=%d startOffset=%d startOffsets=%v sinceLast=%v replay=%v", m.source, paths, m.follow, block, opt.StartOffset, startOffsets, opt.SinceLast, opt.Replay)
+}
+
+// applyStartOptions sets the options that only apply to the first read of
+// the source.
+func (m *Model) applyStartOptions(opt *ingest.Options) {
+	switch opt.Source {
+	case ingest.SourceFile:
+		opt.SinceLast = m.cfg.SinceLast
+		if m.hasTimeRange() && len(opt.Paths) == 1 {
+			// Seek the file to the --since/--until bounds instead of reading it all
+			opt.Since, opt.Until = m.cfg.Since, m.cfg.Until
+			opt.TimeOf = m.preDetectTimeFunc(opt.Paths[0])
+		}
+		if m.cfg.Replay {
+			// Replay from the start of the file
+			m.replayed = true
+			opt.Replay = m.cfg.ReplaySpeed
+			if opt.TimeOf == nil {
+				opt.TimeOf = m.preDetectTimeFunc(opt.Paths[0])
+			}
+			if opt.Follow {
+				opt.StartOffset = 0
+			}
+		}
+	case ingest.SourceCapture:
+		if m.cfg.Replay {
+			m.replayed = true
+			opt.Replay = m.cfg.ReplaySpeed
+		}
+	}
+}
+
+// resumeOffsets returns where following should continue in each file: just
+// past the last line received from it, or the size the file had when the
+// initial load finished when no line was received.
+func (m *Model) resumeOffsets() map[string]int64 {
+	offs := make(map[string]int64, len(m.consumed)+1)
+	for src, end := range m.consumed {
+		offs[src] = end
+	}
+	if primary := m.cfg.PrimaryFile(); len(offs) == 0 && m.fileSizeAtLoad > 0 && m.cfg.Dir == "" {
+		offs[primary] = m.fileSizeAtLoad
+	}
+	return offs
+}
+
+// detectSchema picks the schema for a sample of lines: offline heuristics,
+// then --format overrides, then the per-file schema cache.
+func (m *Model) detectSchema(sample []string) model.Schema {
+	// Heuristics
+	g := detect.Heuristics(sample)
+	schema := g.Schema
+	logx.Infof("detect: heuristics format=%s strategy=%s conf=%.2f", schema.FormatName, schema.ParseStrategy, g.Confidence)
+	if m.cfg.ForceFormat != "" {
+		switch m.cfg.ForceFormat {
+		case "json":
+			schema.ParseStrategy = "json"
+		case "logfmt":
+			schema.ParseStrategy = "logfmt"
+		case "apache":
+			schema = detect.Heuristics([]string{"127.0.0.1 - - [01/Jan/2025:12:00:02 +0000] \"GET / HTTP/1.1\" 200 1234 \"-\" \"curl/8.0\""}).Schema
+		case "syslog":
+			schema = detect.Heuristics([]string{"<34>1 2025-01-01T00:00:00Z h a - - - msg"}).Schema
+		case "syslog3164":
+			schema = detect.Heuristics([]string{"<34>Jan  1 00:00:00 h a[1]: msg"}).Schema
+		case "regex":
+			if m.cfg.RegexPattern != "" {
+				schema = model.Schema{FormatName: "regex", ParseStrategy: "regex", RegexPattern: m.cfg.RegexPattern, LevelMapping: map[string]string{}}
+			}
+		case "csv":
+			if d, ok := detect.ForcedDelimited(sample); ok {
+				schema = d.Schema
+			} else {
+				schema = model.Schema{FormatName: "csv", ParseStrategy: "csv", Delimiter: ","}
+			}
+		}
+		if len(schema.Variants) > 0 && schema.ParseStrategy != "mixed" {
+			// Forced to one format: drop the other candidates
+			schema.FormatName, schema.Variants = m.cfg.ForceFormat, nil
+		}
+		// Keep the container envelope when only the payload format is forced
+		if g.Schema.Envelope != "" && schema.Envelope == "" {
+			schema.Envelope = g.Schema.Envelope
+		}
+		logx.Infof("detect: forced format=%s -> strategy=%s", m.cfg.ForceFormat, schema.ParseStrategy)
+	}
+	// If online and a file path is provided, try schema cache before creating parser
+	// An explicit --regex-pattern is not replaced by a cached schema
+	if primary := m.cfg.PrimaryFile(); !m.cfg.NoCache && m.cfg.RegexPattern == "" && strings.TrimSpace(primary) != "" {
+		if cs, ok := detect.LoadSchemaFromCache(primary); ok {
+			schema = cs
+			logx.Infof("detect: cache hit for %s -> format=%s strategy=%s", primary, schema.FormatName, schema.ParseStrategy)
+		} else {
+			logx.Infof("detect: no cache for %s", primary)
+		}
+	} else if m.cfg.NoCache {
+		logx.Infof("detect: cache disabled via --no-cache")
+	}
+	return schema
+}
+
 type detectedMsg struct{}
 type tickMsg struct{}
 type redetectMsg struct{ schema model.Schema }
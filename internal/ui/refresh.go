@@ -9,7 +9,6 @@ import (
 
 	"logsense/internal/filter"
 	"logsense/internal/model"
-	"logsense/internal/parse"
 	"logsense/internal/util/logx"
 )
 
@@ -69,7 +68,7 @@ func (m *Model) refreshFiltered() {
 			// First cell: invalid marker
 			row = append(row, "·")
 			// Spread raw text across all data columns using their visible widths
-			r := []rune(e.Raw)
+			r := []rune(oneLine(e.Raw))
 			pos := 0
 			for j := range cols {
 				cw := 0
@@ -91,9 +90,12 @@ func (m *Model) refreshFiltered() {
 			// First cell: blank marker for alignment
 			row = append(row, " ")
 			for _, c := range cols {
-				cell := getCol(e, c)
+				cell := oneLine(getCol(e, c))
 				row = append(row, cell)
 			}
+			if e.Marker {
+				placeNotice(row[1:], oneLine(e.Raw))
+			}
 		}
 		rows = append(rows, row)
 	}
@@ -121,6 +123,19 @@ func (m *Model) deriveColumns() []string {
 	// Prefer schema-defined order when available (e.g., after LLM or heuristics)
 	if len(m.schema.Fields) > 0 {
 		cols := m.schema.ColumnOrder()
+		if len(m.schema.Variants) > 0 || m.schema.ParseStrategy == "csv" {
+			// Mixed streams: formats first seen after detection add their
+			// columns; delimited logs: rows wider than the header add col<N>
+			have := make(map[string]bool, len(cols))
+			for _, c := range cols {
+				have[c] = true
+			}
+			for _, c := range m.discovered {
+				if !have[c] {
+					cols = append(cols, c)
+				}
+			}
+		}
 		if len(cols) > 0 {
 			return cols
 		}
@@ -270,6 +285,12 @@ func (m *Model) updateDiscoveryFromEntry(e model.LogEntry) bool {
 	return changed
 }
 
+// multiSource reports whether entries come from several inputs, in which
+// case the source column is shown so lines can be told apart and filtered.
+func (m *Model) multiSource() bool {
+	return len(m.cfg.FilePaths) > 1 || m.cfg.RotatedBase != "" || m.cfg.Dir != "" || m.cfg.HasOtherInput()
+}
+
 func (m *Model) applyColumns(cols []string) {
 	// Compute column widths to fit terminal (data columns only)
 	widths := m.computeWidths(cols)
@@ -533,7 +554,7 @@ func (m *Model) nextStatsField() string {
 func (m *Model) applyNewSchema(s model.Schema, reason string) {
 	logx.Infof("schema: applying new schema via %s: format=%s strategy=%s", reason, s.FormatName, s.ParseStrategy)
 	m.schema = s
-	p, _ := parse.NewParser(m.schema, m.cfg.TimeLayout)
+	p, _ := m.newParser(m.schema)
 	m.parser = p
 	// Re-parse existing buffer
 	old, _, _ := m.ring.Snapshot()
@@ -546,7 +567,18 @@ func (m *Model) applyNewSchema(s model.Schema, reason string) {
 	fieldSet := map[string]struct{}{}
 	var sampleRow map[string]any
 	for i := range old {
+		if old[i].Marker {
+			nr.Push(old[i])
+			continue
+		}
 		e := p.Parse(old[i].Raw, old[i].Source)
+		if e.Header {
+			continue
+		}
+		e.Offset, e.End = old[i].Offset, old[i].End
+		if n, ok := old[i].Fields[truncatedField]; ok {
+			e.Fields[truncatedField] = n
+		}
 		if sampleRow == nil {
 			sampleRow = e.Fields
 		}
@@ -556,6 +588,9 @@ func (m *Model) applyNewSchema(s model.Schema, reason string) {
 			}
 			fieldSet[k] = struct{}{}
 		}
+		if m.multiSource() {
+			fieldSet["source"] = struct{}{}
+		}
 		nr.Push(e)
 		_ = m.updateDiscoveryFromEntry(e)
 	}
@@ -567,17 +602,15 @@ func (m *Model) applyNewSchema(s model.Schema, reason string) {
 			nonGeneric++
 		}
 	}
-	if nonGeneric > 0 {
+	// Delimited logs keep their columns in file order (the parser names
+	// fields after them)
+	if nonGeneric > 0 && m.schema.ParseStrategy != "csv" {
 		keys := make([]string, 0, len(fieldSet))
 		for k := range fieldSet {
 			keys = append(keys, k)
 		}
 		sort.Strings(keys)
-		fdefs := make([]model.FieldDef, 0, len(keys))
-		for _, k := range keys {
-			fdefs = append(fdefs, model.FieldDef{Name: k, Type: "string", Description: "", PathOrGroup: k})
-		}
-		m.schema.Fields = fdefs
+		m.schema.Fields = fieldDefsFor(m.schema.Fields, keys)
 		if sampleRow != nil {
 			m.schema.SampleParsedRow = sampleRow
 		}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/charmbracelet/bubbles/viewport"
 	"github.com/charmbracelet/lipgloss"
+
+	"logsense/internal/model"
 )
 
 func (m *Model) View() string {
@@ -34,7 +36,7 @@ func (m *Model) renderStream() string {
 	hint := "[?]=help"
 	if m.inlineMode == inlineFilter {
 		hint += "[enter]=apply [esc]=cancel"
-	} else if m.inlineMode == inlineBuffer {
+	} else if m.inlineMode == inlineBuffer || m.inlineMode == inlineGoto {
 		hint += "[enter]=apply [esc]=cancel"
 	}
 	// Current cursor position among filtered rows
@@ -58,11 +60,15 @@ func (m *Model) renderStream() string {
 	if rate >= 0.05 { // avoid noise
 		rateStr = fmt.Sprintf("%.1f/s", rate)
 	}
-	status := fmt.Sprintf("[%s] | line:%d/%d rate:%s follow:%v | %s | %s",
-		map[state]string{stateRunning: "Running", statePaused: "Paused"}[m.state],
+	stateStr := map[state]string{stateRunning: "Running", statePaused: "Paused"}[m.state]
+	if m.state == statePaused && m.pausedNew > 0 {
+		stateStr += fmt.Sprintf(" +%d new since pause", m.pausedNew)
+	}
+	status := fmt.Sprintf("[%s] | line:%d/%d rate:%s follow:%v%s | %s | %s",
+		stateStr,
 		curDisp, total,
 		rateStr,
-		m.follow, hint, m.lastMsg)
+		m.follow, m.ingestStatus(), hint, m.lastMsg)
 	// Inline input line above status bar (or active filter summary)
 	var bottom string
 	if m.inlineMode == inlineSearch {
@@ -96,6 +102,8 @@ func (m *Model) renderStream() string {
 		bottom = fmt.Sprintf("Filter %s: %s    [enter]=apply [esc]=cancel [F]=clear filter", field, m.search.View())
 	} else if m.inlineMode == inlineBuffer {
 		bottom = fmt.Sprintf("Max buffer (lines): %s    [enter]=apply [esc]=cancel", m.search.View())
+	} else if m.inlineMode == inlineGoto {
+		bottom = fmt.Sprintf("Go to time (e.g. 14:02, 2025-01-01 14:02, 30m): %s    [enter]=go [esc]=cancel", m.search.View())
 	} else if m.criteria.Query != "" || m.criteria.Field != "" {
 		// Show active filter summary when a filter is applied
 		field := m.criteria.Field
@@ -121,6 +129,47 @@ func (m *Model) renderStream() string {
 	return lipgloss.JoinVertical(lipgloss.Left, tv, bottom, m.styles.Status.Render(status))
 }
 
+// ingestStatus returns extra status bar segments reported by the ingest
+// (e.g. supervised process state), prefixed with a space when non-empty.
+func (m *Model) ingestStatus() string {
+	parts := []string{}
+	if pos := m.filePosition(); pos != "" {
+		parts = append(parts, pos)
+	}
+	if at, speed := m.ingestState.Replay(); !at.IsZero() {
+		parts = append(parts, fmt.Sprintf("replay:%s @%gx", at.Format("2006-01-02 15:04:05"), speed))
+	}
+	if n := m.ingestState.Rotations(); n > 0 {
+		parts = append(parts, fmt.Sprintf("rotations:%d", n))
+	}
+	if m.truncated > 0 {
+		parts = append(parts, fmt.Sprintf("truncated:%d", m.truncated))
+	}
+	if proc, restarts := m.ingestState.Process(); proc != "" {
+		seg := "proc:" + proc
+		if restarts > 0 {
+			seg += fmt.Sprintf(" restarts:%d", restarts)
+		}
+		parts = append(parts, seg)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " " + strings.Join(parts, " ")
+}
+
+// variantNames lists the formats of a mixed schema, e.g. " [json_lines, logfmt]".
+func variantNames(s model.Schema) string {
+	if len(s.Variants) == 0 {
+		return ""
+	}
+	names := make([]string, 0, len(s.Variants))
+	for _, v := range s.Variants {
+		names = append(names, v.FormatName)
+	}
+	return " [" + strings.Join(names, ", ") + "]"
+}
+
 func (m *Model) renderFilters() string {
 	return lipgloss.JoinVertical(lipgloss.Left,
 		m.styles.Base.Render("Search:"),
@@ -295,7 +344,7 @@ func (m *Model) renderModal() string {
 		// Fixed status header above navigable application log viewport
 		header := []string{
 			"Status:",
-			fmt.Sprintf("format: %s (%s)", m.schema.FormatName, m.schema.ParseStrategy),
+			fmt.Sprintf("format: %s (%s)%s", m.schema.FormatName, m.schema.ParseStrategy, variantNames(m.schema)),
 			fmt.Sprintf("rows: %d  ingested: %d  overflow: %d  invalid: %d", len(m.filtered), m.total, m.dropped, m.invalidCount),
 			fmt.Sprintf("source: %s  follow: %v", m.source, m.follow),
 		}
@@ -0,0 +1,171 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"logsense/internal/config"
+	"logsense/internal/ingest"
+	"logsense/internal/model"
+	"logsense/internal/parse"
+	"logsense/internal/util/logx"
+)
+
+// gotoTimeMsg carries the block read from disk around a "go to time" target.
+type gotoTimeMsg struct {
+	target  time.Time
+	entries []model.LogEntry
+	err     error
+}
+
+// parserTimeFunc adapts a parser into a record time extractor for seeking.
+func parserTimeFunc(p parse.Parser) ingest.TimeFunc {
+	return func(line string) (time.Time, bool) {
+		e := p.Parse(line, "")
+		if e.Timestamp == nil {
+			return time.Time{}, false
+		}
+		return *e.Timestamp, true
+	}
+}
+
+// backgroundParser returns a parser for the current schema that is not
+// shared with the tick handler, for commands that parse off the UI loop.
+func (m *Model) backgroundParser() parse.Parser {
+	p, err := m.newParser(m.schema)
+	if err != nil {
+		return m.parser
+	}
+	return p
+}
+
+// hasTimeRange reports whether --since or --until was given.
+func (m *Model) hasTimeRange() bool {
+	return !m.cfg.Since.IsZero() || !m.cfg.Until.IsZero()
+}
+
+// preDetectTimeFunc detects the format from the head of path so the file can
+// be seeked by time before it is read.
+func (m *Model) preDetectTimeFunc(path string) ingest.TimeFunc {
+	head, err := ingest.HeadLines(path, 200, m.cfg.Encoding)
+	if err != nil || len(head) == 0 {
+		logx.Warnf("seek: cannot sample %s for time bounds: %v", path, err)
+		return nil
+	}
+	p, err := m.newParser(m.detectSchema(head))
+	if err != nil {
+		return nil
+	}
+	return parserTimeFunc(p)
+}
+
+// inTimeRange applies --since/--until to entries from sources that cannot be
+// seeked. Entries without a timestamp are kept.
+func (m *Model) inTimeRange(e model.LogEntry) bool {
+	if e.Timestamp == nil {
+		return true
+	}
+	if !m.cfg.Since.IsZero() && e.Timestamp.Before(m.cfg.Since) {
+		return false
+	}
+	if !m.cfg.Until.IsZero() && e.Timestamp.After(m.cfg.Until) {
+		return false
+	}
+	return true
+}
+
+// gotoTime moves the cursor to the first entry at or after the time typed in
+// the prompt. When the ring does not cover it and the source is a plain file,
+// the file is seeked through its index and the ring reloaded from there.
+func (m *Model) gotoTime(input string) tea.Cmd {
+	target, err := config.ParseTimeBound(input, time.Now())
+	if err != nil {
+		m.lastMsg = err.Error()
+		return nil
+	}
+	if m.selectTime(target) {
+		return nil
+	}
+	ix := m.pagingIndex()
+	if ix == nil {
+		m.lastMsg = fmt.Sprintf("no entries at %s in the buffer", target.Format(time.RFC3339))
+		return nil
+	}
+	m.paging = true
+	m.lastMsg = "seeking " + target.Format(time.RFC3339) + "..."
+	// The seek and the index sampling run concurrently with each other and
+	// with the tick handler, so each gets its own parser
+	parser := m.backgroundParser()
+	ix.SetTimeFunc(parserTimeFunc(m.backgroundParser()))
+	opt := ingest.Options{ScanBufSize: m.scanBufSize, Multiline: m.multiline, Encoding: m.cfg.Encoding}
+	return func() tea.Msg {
+		timeOf := parserTimeFunc(parser)
+		off, err := ingest.SeekTime(m.ctx, ix.Path(), func(t time.Time) bool { return !t.Before(target) }, timeOf, ix)
+		if err != nil {
+			return gotoTimeMsg{target: target, err: err}
+		}
+		// Start a little earlier so there is context above the target
+		lines, err := ingest.ReadBlockBefore(m.ctx, ix.Path(), off, pageBlockBytes/4, opt)
+		if err != nil {
+			return gotoTimeMsg{target: target, err: err}
+		}
+		after, err := ingest.ReadBlockAfter(m.ctx, ix.Path(), off, pageBlockBytes, opt)
+		lines = append(lines, after...)
+		entries := make([]model.LogEntry, 0, len(lines))
+		for _, l := range lines {
+			if e := parseLine(parser, l); !e.Header {
+				entries = append(entries, e)
+			}
+		}
+		return gotoTimeMsg{target: target, entries: entries, err: err}
+	}
+}
+
+// selectTime puts the cursor on the first filtered entry at or after t when
+// the buffer spans t.
+func (m *Model) selectTime(t time.Time) bool {
+	var first, last *time.Time
+	for i := range m.filtered {
+		if ts := m.filtered[i].Timestamp; ts != nil {
+			if first == nil {
+				first = ts
+			}
+			last = ts
+		}
+	}
+	if first == nil || t.Before(*first) || t.After(*last) {
+		return false
+	}
+	for i, e := range m.filtered {
+		if e.Timestamp != nil && !e.Timestamp.Before(t) {
+			m.tbl.SetCursor(i)
+			m.ensureCursorVisible()
+			m.lastMsg = "at " + e.Timestamp.Format(time.RFC3339)
+			return true
+		}
+	}
+	return false
+}
+
+// applyGotoTime replaces the ring with the block read around the target.
+func (m *Model) applyGotoTime(msg gotoTimeMsg) {
+	m.paging = false
+	if msg.err != nil {
+		logx.Warnf("seek: %v", msg.err)
+		m.lastMsg = fmt.Sprintf("seek failed: %v", msg.err)
+		return
+	}
+	if len(msg.entries) == 0 {
+		m.lastMsg = "no entries at or after " + msg.target.Format(time.RFC3339)
+		return
+	}
+	m.ring = model.NewRing(m.ring.Cap())
+	m.ring.Append(msg.entries)
+	m.prevDropped, m.dropped = 0, 0
+	m.refreshFiltered()
+	if !m.selectTime(msg.target) {
+		m.lastMsg = "jumped near " + msg.target.Format(time.RFC3339)
+	}
+}
@@ -57,6 +57,7 @@ const (
 	inlineSearch
 	inlineFilter
 	inlineBuffer
+	inlineGoto
 )
 
 type Model struct {
@@ -66,20 +67,39 @@ type Model struct {
 	ingestCancel context.CancelFunc
 	// fileSizeAtLoad stores file size after completing non-follow drain; used to pick up missing lines when enabling follow
 	fileSizeAtLoad int64
-	// tailStartOffset allows passing a specific start offset to tail when (re)starting ingest in follow mode
-	tailStartOffset int64
 
 	// Pipeline
-	lines  <-chan ingest.Line
-	errs   <-chan error
-	parser parse.Parser
-	schema model.Schema
+	// ingestState carries runtime status from ingest (e.g. supervised process state)
+	ingestState *ingest.State
+	lines       <-chan ingest.Line
+	errs        <-chan error
+	parser      parse.Parser
+	schema      model.Schema
+	// detected is set once detection has pushed the lines it buffered;
+	// ingestDone once lines is closed and everything read is in the ring
+	detected   bool
+	ingestDone bool
 
 	// Data
-	ring     *model.Ring
-	filtered []model.LogEntry
-	total    uint64
-	dropped  uint64
+	ring *model.Ring
+	// paging is set while a block is read back from disk; posCache keeps the
+	// last offset to line number lookup for the status bar
+	paging   bool
+	posCache struct{ offset, line int64 }
+	// consumed is the end offset of the last line received per file (keyed
+	// by source tag), used to resume following and saved as a read
+	// checkpoint on exit; replayed is set when the source was replayed
+	consumed map[string]int64
+	replayed bool
+	// recorder captures raw input for --record
+	recorder *ingest.Recorder
+	// multiline is the continuation-joining setup built from the --multiline flags
+	multiline ingest.MultilineOptions
+	filtered  []model.LogEntry
+	total     uint64
+	dropped   uint64
+	// truncated counts lines cut to the line limit (see --long-lines)
+	truncated int
 
 	// UI
 	tab        tab
@@ -103,6 +123,8 @@ type Model struct {
 	// Dirty flags to minimize rebuilds
 	rowsDirty    bool
 	columnsDirty bool
+	// pausedNew counts entries that reached the ring while the view was paused
+	pausedNew int
 
 	// Filter
 	criteria filter.Criteria
@@ -3,6 +3,7 @@ package ui
 import (
 	"context"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -34,6 +35,7 @@ func (m *Model) buildHelpItems() []helpItem {
 		{group: "Navigation", text: "Page down", key: tea.Key{Type: tea.KeyPgDown}},
 		{group: "Navigation", text: "Go to top", key: km.Top},
 		{group: "Navigation", text: "Go to bottom", key: km.Bottom},
+		{group: "Navigation", text: "Go to time", key: km.GotoTime},
 		{group: "Navigation", text: "Previous column", key: tea.Key{Type: tea.KeyLeft}},
 		{group: "Navigation", text: "Next column", key: tea.Key{Type: tea.KeyRight}},
 		{group: "Columns", text: "Increase column width", key: km.IncColWidth},
@@ -52,6 +54,7 @@ func (m *Model) buildHelpItems() []helpItem {
 		{group: "Views", text: "Stats for column", key: m.keymap.Stats},
 
 		{group: "Control", text: "Pause/Resume", key: km.Pause},
+		{group: "Control", text: "Resume at newest", key: km.Latest},
 		{group: "Control", text: "Toggle follow", key: km.Follow},
 		{group: "Control", text: "Change buffer size", key: km.Buffer},
 		{group: "Control", text: "Export", key: km.Export},
@@ -277,7 +280,7 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, cmd
 		}
 		// Inline input handling for search/filter/buffer (bottom line)
-		if m.inlineMode == inlineSearch || m.inlineMode == inlineFilter || m.inlineMode == inlineBuffer {
+		if m.inlineMode == inlineSearch || m.inlineMode == inlineFilter || m.inlineMode == inlineBuffer || m.inlineMode == inlineGoto {
 			// Enter applies; Esc cancels
 			if msg.Type == tea.KeyEnter {
 				q := strings.TrimSpace(m.search.Value())
@@ -345,11 +348,18 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.search.SetValue("")
 					m.inlineMode = inlineNone
 					return m, nil
+				} else if m.inlineMode == inlineGoto {
+					m.search.SetValue("")
+					m.inlineMode = inlineNone
+					if q == "" {
+						return m, nil
+					}
+					return m, m.gotoTime(q)
 				}
 				return m, nil
 			}
 			if msg.Type == tea.KeyEsc {
-				if m.inlineMode == inlineBuffer {
+				if m.inlineMode == inlineBuffer || m.inlineMode == inlineGoto {
 					m.search.SetValue("")
 				}
 				m.inlineMode = inlineNone
@@ -371,8 +381,8 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					return m, nil
 				}
 				// Do not swallow other keys; allow table/shortcuts to work
-			} else if (m.inlineMode == inlineSearch && m.searchEditing) || m.inlineMode == inlineFilter || m.inlineMode == inlineBuffer {
-				// When editing inline inputs (search/filter/buffer), route all keys
+			} else if (m.inlineMode == inlineSearch && m.searchEditing) || m.inlineMode == inlineFilter || m.inlineMode == inlineBuffer || m.inlineMode == inlineGoto {
+				// When editing inline inputs (search/filter/buffer/goto), route all keys
 				// to the text input and suppress global shortcuts. ESC and Enter
 				// are handled earlier in this function.
 				var cmd tea.Cmd
@@ -422,25 +432,79 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.search.SetValue("")
 			m.search.Focus()
 			return m, nil
+		case keyMatches(msg, m.keymap.GotoTime):
+			m.inlineMode = inlineGoto
+			m.search.SetValue("")
+			m.search.Focus()
+			return m, nil
 		case keyMatches(msg, m.keymap.Pause):
+			// Pausing freezes the view only; ingest keeps filling the ring
 			if m.state == stateRunning {
 				m.state = statePaused
+				m.pausedNew = 0
 			} else {
 				m.state = stateRunning
+				m.rowsDirty = true
+			}
+			return m, nil
+		case keyMatches(msg, m.keymap.Latest):
+			m.state = stateRunning
+			m.pausedNew = 0
+			m.refreshFiltered()
+			m.rowsDirty = false
+			if n := len(m.tbl.Rows()); n > 0 {
+				m.tbl.SetCursor(n - 1)
+				m.ensureCursorVisible()
 			}
 			return m, nil
 		case keyMatches(msg, m.keymap.Follow):
-			// Follow only makes sense for files, not stdin/demo
+			// Follow only makes sense for files and commands, not stdin/demo/listeners
 			if m.cfg.UseStdin || m.source == string(ingest.SourceStdin) || m.source == string(ingest.SourceDemo) {
 				m.lastMsg = "follow is not applicable for stdin/demo"
 				return m, nil
 			}
+			if m.source == string(ingest.SourceSyslog) || m.source == string(ingest.SourceHTTP) || m.source == string(ingest.SourceOTLP) {
+				m.lastMsg = "follow is not applicable for network listeners"
+				return m, nil
+			}
+			if m.source == string(ingest.SourceCapture) {
+				m.lastMsg = "follow is not applicable for a loaded capture"
+				return m, nil
+			}
+			if m.source == string(ingest.SourceFile) || m.source == string(ingest.SourceRotated) {
+				// UTF-16 files are decoded as a stream with no offsets to resume from
+				for _, p := range append([]string{m.cfg.RotatedBase}, m.cfg.FilePaths...) {
+					if p != "" && ingest.IsWideEncoded(p, m.cfg.Encoding) {
+						m.lastMsg = "follow is not applicable for UTF-16 files"
+						return m, nil
+					}
+				}
+			}
 			m.follow = !m.follow
-			// Restart pipeline with new follow state; preserve tail offset if possible
-			if m.follow {
-				m.tailStartOffset = -1 // let ingest pick current end
+			if m.source == string(ingest.SourceCommand) {
+				// Commands keep running; follow only controls restart on exit
+				m.ingestState.SetSupervise(m.follow)
+				if proc, _ := m.ingestState.Process(); m.follow && (strings.HasPrefix(proc, "exited") || strings.HasPrefix(proc, "failed")) {
+					m.lastMsg = "restarting command"
+				} else if m.follow {
+					m.lastMsg = "command will be restarted when it exits"
+				} else {
+					m.lastMsg = "command will not be restarted"
+				}
+				return m, nil
+			}
+			if !m.follow {
+				// Stop reading; what was read stays in the buffer
+				if m.ingestCancel != nil {
+					m.ingestCancel()
+					m.ingestCancel = nil
+				}
+				return m, nil
 			}
-			return m, setupPipeline(m)
+			// Continue each file right after the last line received, keeping
+			// the detected parser
+			m.startIngest(m.resumeOffsets())
+			return m, nil
 		case keyMatches(msg, m.keymap.Export):
 			if m.cfg.ExportFormat != "" && m.cfg.ExportOut != "" {
 				go func() {
@@ -509,13 +573,24 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 		case keyMatches(msg, m.keymap.Top):
+			if m.tbl.Cursor() == 0 {
+				return m, m.pageOlder()
+			}
 			m.tbl.SetCursor(0)
 			return m, nil
 		case keyMatches(msg, m.keymap.Bottom):
 			if n := len(m.tbl.Rows()); n > 0 {
+				if m.tbl.Cursor() == n-1 {
+					return m, m.pageNewer()
+				}
 				m.tbl.SetCursor(n - 1)
 			}
 			return m, nil
+		case (msg.Type == tea.KeyUp || msg.Type == tea.KeyPgUp) && m.tbl.Cursor() == 0 && m.pagingIndex() != nil:
+			// Scrolling past the top of the ring pages older entries in from disk
+			return m, m.pageOlder()
+		case (msg.Type == tea.KeyDown || msg.Type == tea.KeyPgDown) && m.tbl.Cursor() == len(m.tbl.Rows())-1 && m.pagingIndex() != nil:
+			return m, m.pageNewer()
 		case keyMatches(msg, m.keymap.Explain):
 			// Trigger OpenAI explanation for the selected log entry
 			if m.cfg.Offline || m.cfg.OpenAIKey() == "" {
@@ -586,11 +661,15 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 	case detectedMsg:
+		m.detected = true
 		drain := func() tea.Msg {
 			// Drain remaining lines when not following; record file size
 			if !m.follow {
 				// Wait briefly for ingest to finish
 				time.Sleep(200 * time.Millisecond)
+				if st, err := os.Stat(m.cfg.PrimaryFile()); err == nil && !st.IsDir() {
+					m.fileSizeAtLoad = st.Size()
+				}
 				m.netBusy = false
 				m.lastMsg = ""
 			}
@@ -631,6 +710,12 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.tbl.SetCursor(n - 1)
 		}
 		return m, drain
+	case pagedMsg:
+		m.applyPage(msg)
+		return m, nil
+	case gotoTimeMsg:
+		m.applyGotoTime(msg)
+		return m, nil
 	case loadDoneMsg:
 		m.netBusy = false
 		m.lastMsg = ""
@@ -657,11 +742,11 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.lastMsg = fmt.Sprintf("✅ OpenAI schema: %s (%.0f%%)", m.schema.FormatName, m.schema.Confidence*100)
 			logx.Infof("openai: success format=%s strategy=%s conf=%.2f", m.schema.FormatName, m.schema.ParseStrategy, m.schema.Confidence)
 			// Save to cache if enabled and we have a file path
-			if !m.cfg.NoCache && strings.TrimSpace(m.cfg.FilePath) != "" {
-				if err := detectSaveSchema(m.cfg.FilePath, m.schema); err != nil {
+			if primary := m.cfg.PrimaryFile(); !m.cfg.NoCache && strings.TrimSpace(primary) != "" {
+				if err := detectSaveSchema(primary, m.schema); err != nil {
 					logx.Warnf("detect: failed to save schema cache: %v", err)
 				} else {
-					logx.Infof("detect: schema cached for %s", m.cfg.FilePath)
+					logx.Infof("detect: schema cached for %s", primary)
 				}
 			} else if m.cfg.NoCache {
 				logx.Infof("detect: not caching schema due to --no-cache")
@@ -698,43 +783,50 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 	case tickMsg:
-		// Pull lines non-blocking, parse, push to ring
-		if m.state == stateRunning {
-			added := 0
-			for i := 0; i < 500; i++ { // limit per tick
-				select {
-				case l, ok := <-m.lines:
-					if !ok {
-						break
+		// Pull lines non-blocking, parse, push to ring. This goes on while
+		// paused so producers (stdin, commands) are never blocked.
+		added := 0
+		for i := 0; i < 500; i++ { // limit per tick
+			select {
+			case l, ok := <-m.lines:
+				if !ok {
+					m.ingestDone = m.detected
+					i = 999999
+					break
+				}
+				if m.parser != nil {
+					e := m.entryFromLine(l)
+					if e.Header || !m.inTimeRange(e) {
+						continue
 					}
-					if m.parser != nil {
-						e := m.parser.Parse(l.Text, l.Source)
-						m.ring.Push(e)
-						if m.updateDiscoveryFromEntry(e) {
-							m.columnsDirty = true
-						}
-						m.rowsDirty = true
-						added++
+					m.ring.Push(e)
+					if m.updateDiscoveryFromEntry(e) {
+						m.columnsDirty = true
 					}
-				default:
-					i = 999999 // break outer
+					m.rowsDirty = true
+					added++
 				}
+			default:
+				i = 999999 // break outer
 			}
-			// Update smoothed entry rate (lines/sec)
-			now := time.Now()
-			if m.rateLast.IsZero() {
-				m.rateLast = now
-			} else {
-				dt := now.Sub(m.rateLast).Seconds()
-				if dt > 0 {
-					inst := float64(added) / dt
-					if m.rateEWMA == 0 {
-						m.rateEWMA = inst
-					} else {
-						m.rateEWMA = 0.85*m.rateEWMA + 0.15*inst
-					}
-					m.rateLast = now
+		}
+		if m.state == statePaused {
+			m.pausedNew += added
+		}
+		// Update smoothed entry rate (lines/sec)
+		now := time.Now()
+		if m.rateLast.IsZero() {
+			m.rateLast = now
+		} else {
+			dt := now.Sub(m.rateLast).Seconds()
+			if dt > 0 {
+				inst := float64(added) / dt
+				if m.rateEWMA == 0 {
+					m.rateEWMA = inst
+				} else {
+					m.rateEWMA = 0.85*m.rateEWMA + 0.15*inst
 				}
+				m.rateLast = now
 			}
 		}
 		// Drain ingest errors
@@ -745,18 +837,15 @@ func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					j = 999999
 					break
 				}
-				if strings.Contains(strings.ToLower(err.Error()), "token too long") {
-					logx.Errorf("ingest error: %v (increase --max-buffer above %d bytes)", err, m.scanBufSize)
-				} else {
-					logx.Errorf("ingest error: %v", err)
-				}
+				logx.Errorf("ingest error: %v", err)
 			default:
 				j = 999999
 			}
 		}
-		// Refresh when flagged dirty or if table has no rows but we have data
-		doRefresh := m.rowsDirty || m.columnsDirty
-		if !doRefresh {
+		// Refresh when flagged dirty or if table has no rows but we have data;
+		// a paused view stays as it is
+		doRefresh := m.state == stateRunning && (m.rowsDirty || m.columnsDirty)
+		if !doRefresh && m.state == stateRunning {
 			if nRows := len(m.tbl.Rows()); nRows == 0 {
 				if ents, _, _ := m.ring.Snapshot(); len(ents) > 0 {
 					doRefresh = true
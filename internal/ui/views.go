@@ -99,6 +99,8 @@ func buildStats(field string, entries []model.LogEntry) string {
 			} else {
 				counts[t]++
 			}
+		case bool:
+			counts[strconv.FormatBool(t)]++
 		default:
 			// ignore
 		}
@@ -272,6 +274,8 @@ func computeStatsItems(field string, entries []model.LogEntry) []statItem {
 			} else {
 				counts[t]++
 			}
+		case bool:
+			counts[strconv.FormatBool(t)]++
 		}
 	}
 	items := []statItem{}
@@ -530,8 +534,11 @@ func buildTimeDistribution(field string, items []statItem, sel int, entries []mo
 			}
 			return f == it.fvalue
 		}
-		// Categorical string
+		// Categorical string (or logfmt flag)
 		s, ok := v.(string)
+		if b, isBool := v.(bool); isBool {
+			s, ok = strconv.FormatBool(b), true
+		}
 		if !ok {
 			return false
 		}